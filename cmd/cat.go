@@ -4,11 +4,12 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"fmt"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
 
+	"github.com/DnFreddie/gosh/internal/config"
 	"github.com/DnFreddie/gosh/pkg/busybox"
 	"github.com/spf13/cobra"
 )
@@ -28,31 +29,38 @@ to quickly create a Cobra application.`,
 		if len(args) == 0 {
 			return cmd.Usage()
 		}
-		for _, v := range args {
-			filePath := v
-			abs, err := filepath.Abs(filePath)
-			if err != nil {
-				fmt.Println(err)
-				continue
+		cfg := config.Get().Cat
+		var errs []error
+		for _, filePath := range args {
+			err := catFile(filePath, cfg)
+			if errors.Is(err, busybox.ErrCancelled) || errors.Is(err, busybox.ErrInterrupted) {
+				return err
 			}
-			f, err := os.Open(abs)
-			defer func() {
-				f.Close()
-			}()
 			if err != nil {
-				fmt.Println(err)
-				continue
-			}
-			h := busybox.NewHighlightedPager(filePath, io.Reader(f))
-			if err := h.Run(); err != nil {
-				fmt.Println(err)
+				errs = append(errs, err)
 			}
 		}
-
-		return nil
+		return errors.Join(errs...)
 	},
 }
 
+func catFile(filePath string, cfg config.Cat) error {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := busybox.NewHighlightedPager(filePath, io.Reader(f)).
+		SetStyle(cfg.Style).
+		SetPipeStyle(cfg.PipeStyle)
+	return h.Run()
+}
+
 func init() {
 	rootCmd.AddCommand(catCmd)
 
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/DnFreddie/gosh/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// configCmd groups the configuration helpers
+var configCmd = &cobra.Command{
+	Use:   "config [show/path]",
+	Short: "Inspect the gosh configuration",
+	Long: `gosh reads $XDG_CONFIG_HOME/gosh/config.toml (~/.config/gosh/config.toml)
+or the file given with --config or GOSH_CONFIG.
+
+Every key can be overridden with an environment variable named
+GOSH_<SECTION>_<KEY>, e.g. GOSH_SESSIONIZER_DEPTH=4 or GOSH_SNIP_DIR=~/snippets.
+Lists are comma separated.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:          "show",
+	Short:        "Print the effective configuration as TOML",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		if src := config.Source(); src != "" {
+			fmt.Fprintf(out, "# loaded from %s\n\n", src)
+		} else {
+			fmt.Fprintf(out, "# no config file found, using defaults (%s)\n\n", config.Path())
+		}
+		return config.Get().Write(out)
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the config file gosh looks for",
+	Run: func(cmd *cobra.Command, args []string) {
+		path := cfgFile
+		if path == "" {
+			path = config.Path()
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configPathCmd)
+}
@@ -6,19 +6,20 @@ package cmd
 import (
 	"strings"
 
+	"github.com/DnFreddie/gosh/internal/config"
 	"github.com/DnFreddie/gosh/internal/editor"
 	"github.com/spf13/cobra"
 )
 
 // fmCmd represents the fm command
 var editCmd = &cobra.Command{
-	Use:     "edit [br/]",
-	Short:   "Edit with vim",
-	Long:    `Edit with vim or vi if not available `,
-	Aliases: []string{"e"},
-	Run: func(cmd *cobra.Command, args []string) {
-		editor.Edit(strings.Join(args, " "))
-
+	Use:          "edit [br/]",
+	Short:        "Edit with vim",
+	Long:         `Edit with vim or vi if not available `,
+	Aliases:      []string{"e"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return editor.Edit(strings.Join(args, " "), config.Get().Edit)
 	},
 }
 
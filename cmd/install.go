@@ -1,12 +1,17 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path"
 	"path/filepath"
 
+	"github.com/DnFreddie/gosh/internal/config"
+	"github.com/DnFreddie/gosh/pkg/busybox"
 	"github.com/DnFreddie/gosh/pkg/installer"
 	"github.com/spf13/cobra"
 )
@@ -17,12 +22,14 @@ var completionCmd = &cobra.Command{
 	Short: "Generate shell completions for a command",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		shell, err := cmd.Parent().Flags().GetString("shell")
+		cfg := config.Get().Install
+
+		shell, err := stringFlag(cmd.Parent(), "shell", cfg.Shell)
 		if err != nil {
 			return fmt.Errorf("error getting shell type: %w", err)
 		}
 
-		completionDir, err := cmd.Parent().Flags().GetString("completion-dir")
+		completionDir, err := stringFlag(cmd.Parent(), "completion-dir", cfg.CompletionDir)
 		if err != nil {
 			return fmt.Errorf("error getting completion directory: %w", err)
 		}
@@ -35,7 +42,7 @@ var completionCmd = &cobra.Command{
 			return fmt.Errorf("failed to generate completion: %w", err)
 		}
 
-		fmt.Printf("Generated %s completion for %s\n", shell, args[0])
+		slog.Info("Generated completion", "shell", shell, "command", args[0])
 		return nil
 	},
 }
@@ -50,14 +57,18 @@ Example usage:
   gosh install mikefarah/yq DnFreddie/gosh
   gosh install --target ~/.local/bin mikefarah/yq
   gosh install cli/cli:gh
-  gosh install --toolbox`,
+  gosh install --toolbox
+  gosh install --pick
+  gosh install --output json mikefarah/yq | jq -r '.[].files[]'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		targetDir, err := cmd.Flags().GetString("target")
+		cfg := config.Get().Install
+
+		targetDir, err := stringFlag(cmd, "target", cfg.TargetDir)
 		if err != nil {
 			return fmt.Errorf("error getting target directory: %w", err)
 		}
 
-		tempDir, err := cmd.Flags().GetString("temp")
+		tempDir, err := stringFlag(cmd, "temp", cfg.TempDir)
 		if err != nil {
 			return fmt.Errorf("error getting temp directory: %w", err)
 		}
@@ -67,30 +78,63 @@ Example usage:
 			return fmt.Errorf("error getting toolbox flag: %w", err)
 		}
 
-		if !toolbox && len(args) == 0 {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return fmt.Errorf("error getting output format: %w", err)
+		}
+		if output != "text" && output != "json" {
+			return fmt.Errorf("unknown output format %q, expected text or json", output)
+		}
+
+		pick, err := cmd.Flags().GetBool("pick")
+		if err != nil {
+			return fmt.Errorf("error getting pick flag: %w", err)
+		}
+
+		if !toolbox && !pick && len(args) == 0 {
 			return fmt.Errorf("at least one repository must be specified")
 		}
 
-		config := installer.Config{
+		instConfig := installer.Config{
 			TargetDir: targetDir,
 			TempDir:   tempDir,
 		}
 
 		repos := args
 		if toolbox {
-			repos = installer.TOOLBOX
+			repos = cfg.Toolbox
+		}
+		if pick {
+			picked, err := busybox.RunTermMulti(cfg.Toolbox, busybox.ItemFormatter[string]{
+				ToString: func(s string) string { return s },
+			})
+			if err != nil {
+				return err
+			}
+			repos = append(args, picked...)
 		}
 
-		inst, err := installer.NewInstaller(config, repos)
+		inst, err := installer.NewInstaller(instConfig, repos)
 		if err != nil {
 			return fmt.Errorf("failed to create installer: %w", err)
 		}
 
-		if err := inst.Install(); err != nil {
+		results, err := inst.Install()
+		if err != nil {
 			return fmt.Errorf("installation failed: %w", err)
 		}
 
-		fmt.Println("Installation completed successfully!")
+		if output == "json" {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(results)
+		}
+		for _, r := range results {
+			for _, f := range r.Files {
+				fmt.Fprintln(cmd.OutOrStdout(), f)
+			}
+		}
+		slog.Info("Installation completed successfully!")
 		return nil
 	},
 }
@@ -100,15 +144,16 @@ var snippetCmd = &cobra.Command{
 	Short: "Interact with code snippets",
 	Long:  `The snippet command allows you to manage and use code snippets.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		chosenSnippet, err := installer.ChoseSnippet()
+		chosenSnippet, err := installer.ChoseSnippet(config.Get().Snip.Dir, config.Get().Cat.Style)
 		if err != nil {
 			return err
 		}
 
 		f, err := os.Open(chosenSnippet)
-		defer func() {
-			f.Close()
-		}()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
 		reader := io.Reader(f)
 
 		snippet, err := installer.GetSnippet(reader)
@@ -116,7 +161,7 @@ var snippetCmd = &cobra.Command{
 			return err
 		}
 
-		snippet.PrintSnippet()
+		snippet.PrintSnippet(cmd.ErrOrStderr())
 		pwd, err := os.Getwd()
 		if err != nil {
 			return err
@@ -130,10 +175,7 @@ var snippetCmd = &cobra.Command{
 				return err
 			}
 		} else {
-
-			red := "\033[31m"
-			reset := "\033[0m"
-			return fmt.Errorf(red+"The File already exists: %s"+reset, filepath.Base(filePath))
+			return errors.New(busybox.Alert("The File already exists: " + filepath.Base(filePath)))
 		}
 
 		return nil
@@ -145,19 +187,21 @@ func init() {
 	installCmd.AddCommand(completionCmd)
 	installCmd.AddCommand(snippetCmd)
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: could not determine home directory: %v\n", err)
-		return
-	}
-
-	defaultTargetDir := filepath.Join(homeDir, ".local", "bin")
-	defaultTempDir := os.TempDir()
-	defaultCompletionDir := filepath.Join(homeDir, ".local", "share", "completions")
+	// Defaults live in the config file, see `gosh config show`
+	installCmd.Flags().StringP("target", "t", "", "Target directory for installed binaries (default install.target_dir)")
+	installCmd.Flags().String("temp", "", "Temporary directory for downloads (default install.temp_dir)")
+	installCmd.Flags().Bool("toolbox", false, "Whether to download the install.toolbox repositories")
+	installCmd.Flags().BoolP("pick", "p", false, "Pick the repositories to install from install.toolbox")
+	installCmd.Flags().StringP("output", "o", "text", "Output format for the installed files (text, json)")
+	installCmd.Flags().String("shell", "", "Shell type for completion generation (bash, zsh, fish) (default install.shell)")
+	installCmd.Flags().String("completion-dir", "", "Directory for storing completion files (default install.completion_dir)")
+}
 
-	installCmd.Flags().StringP("target", "t", defaultTargetDir, "Target directory for installed binaries")
-	installCmd.Flags().String("temp", defaultTempDir, "Temporary directory for downloads")
-	installCmd.Flags().Bool("toolbox", false, "Whether to download an entire toolbox")
-	installCmd.Flags().String("shell", "bash", "Shell type for completion generation (bash, zsh, fish)")
-	installCmd.Flags().String("completion-dir", defaultCompletionDir, "Directory for storing completion files")
+// stringFlag returns the value of the flag name when it was given on the
+// command line and fallback otherwise.
+func stringFlag(cmd *cobra.Command, name, fallback string) (string, error) {
+	if !cmd.Flags().Changed(name) {
+		return fallback, nil
+	}
+	return cmd.Flags().GetString(name)
 }
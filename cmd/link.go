@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/DnFreddie/gosh/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// applets maps the names gosh answers to when it is invoked through a
+// symlink (busybox style) to the command they run.
+var applets = map[string][]string{
+	"tn":   {"tn"},
+	"fd":   {"sessionizer", "fd"},
+	"fs":   {"sessionizer", "fs"},
+	"vf":   {"sessionizer", "vf"},
+	"gcat": {"cat"},
+	"snip": {"install", "snip"},
+}
+
+// appletArgs returns the command line to run when gosh was started
+// as argv0, or false if argv0 isn't an applet name.
+func appletArgs(argv0 string, args []string) ([]string, bool) {
+	cmdPath, ok := applets[filepath.Base(argv0)]
+	if !ok {
+		return nil, false
+	}
+	return append(slices.Clone(cmdPath), args...), true
+}
+
+var linkCmd = &cobra.Command{
+	Use:   "link [applet...]",
+	Short: "Create symlinks so each tool can be called directly",
+	Long: `Link creates symlinks named after the gosh tools pointing at the gosh binary.
+When gosh is started through one of them it runs the matching command,
+so "tn" behaves like "gosh tn" and "gcat" like "gosh cat".
+
+Without arguments every applet is linked.
+
+Example usage:
+  gosh link --dir ~/.local/bin
+  gosh link tn gcat
+  gosh link --list`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, err := cmd.Flags().GetBool("list")
+		if err != nil {
+			return fmt.Errorf("error getting list flag: %w", err)
+		}
+		names := slices.Sorted(maps.Keys(applets))
+		if list {
+			for _, name := range names {
+				fmt.Fprintf(cmd.OutOrStdout(), "%-6s gosh %s\n", name, strings.Join(applets[name], " "))
+			}
+			return nil
+		}
+
+		dir, err := stringFlag(cmd, "dir", config.Get().Install.TargetDir)
+		if err != nil {
+			return fmt.Errorf("error getting link directory: %w", err)
+		}
+		dir = config.ExpandPath(dir)
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("error getting force flag: %w", err)
+		}
+
+		if len(args) > 0 {
+			for _, name := range args {
+				if _, ok := applets[name]; !ok {
+					return fmt.Errorf("unknown applet %q, see gosh link --list", name)
+				}
+			}
+			names = args
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("locating the gosh binary: %w", err)
+		}
+		if exe, err = filepath.EvalSymlinks(exe); err != nil {
+			return fmt.Errorf("resolving the gosh binary: %w", err)
+		}
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create link directory: %w", err)
+		}
+
+		var errs []error
+		for _, name := range names {
+			if err := link(exe, filepath.Join(dir, name), force); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s -> %s\n", filepath.Join(dir, name), exe)
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// link points dst at target, leaving an existing link to target alone.
+// Anything else already at dst is only replaced when force is set.
+func link(target, dst string, force bool) error {
+	if current, err := os.Readlink(dst); err == nil && current == target {
+		return nil
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		if !force {
+			return fmt.Errorf("%s already exists, use --force to replace it", dst)
+		}
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dst, err)
+		}
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return fmt.Errorf("failed to link %s: %w", dst, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(linkCmd)
+
+	linkCmd.Flags().StringP("dir", "d", "", "Directory for the symlinks (default install.target_dir)")
+	linkCmd.Flags().BoolP("force", "f", false, "Replace files that already exist")
+	linkCmd.Flags().BoolP("list", "l", false, "List the applets and the commands they run")
+}
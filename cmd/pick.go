@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DnFreddie/gosh/internal/config"
+	"github.com/DnFreddie/gosh/pkg/busybox"
+	"github.com/spf13/cobra"
+)
+
+// Exit statuses of pick, the same as fzf uses
+const (
+	pickNoMatch     = 1
+	pickInterrupted = 130
+)
+
+var pickCmd = &cobra.Command{
+	Use:   "pick",
+	Short: "Pick lines read from stdin, like fzf",
+	Long: `Pick reads candidates from stdin, one per line, lets you choose with the
+gosh picker and prints the choice to stdout. The picker draws on the terminal,
+so pick works in the middle of a pipeline.
+
+With --filter there is no picker, the lines matching the query are printed
+best match first. Pick exits with 1 when nothing matched and with 130 when
+the picker was cancelled.
+
+Example usage:
+  git branch --format='%(refname:short)' | gosh pick | xargs git switch
+  ls | gosh pick --multi
+  ls | gosh pick --filter 'go$'
+  ls | gosh pick --query main --select-1 --exit-0
+  git branch --format='%(refname:short)' | gosh pick --history branches
+  ls | gosh pick --height 40% --reverse --border --header 'Files'`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		multi, err := flags.GetBool("multi")
+		if err != nil {
+			return fmt.Errorf("error getting multi flag: %w", err)
+		}
+		query, err := flags.GetString("query")
+		if err != nil {
+			return fmt.Errorf("error getting query flag: %w", err)
+		}
+		selectOne, err := flags.GetBool("select-1")
+		if err != nil {
+			return fmt.Errorf("error getting select-1 flag: %w", err)
+		}
+		exitZero, err := flags.GetBool("exit-0")
+		if err != nil {
+			return fmt.Errorf("error getting exit-0 flag: %w", err)
+		}
+		history, err := flags.GetString("history")
+		if err != nil {
+			return fmt.Errorf("error getting history flag: %w", err)
+		}
+
+		layout, err := pickLayout(cmd)
+		if err != nil {
+			return err
+		}
+
+		lines, err := readLines(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("error reading candidates: %w", err)
+		}
+
+		formatter := busybox.ItemFormatter[string]{
+			ToString: func(s string) string { return s },
+			Query:    query,
+			History:  history,
+			Layout:   &layout,
+		}
+		out := cmd.OutOrStdout()
+
+		if flags.Changed("filter") {
+			filter, err := flags.GetString("filter")
+			if err != nil {
+				return fmt.Errorf("error getting filter flag: %w", err)
+			}
+			matched := busybox.Filter(lines, formatter, filter)
+			for _, line := range matched {
+				fmt.Fprintln(out, line)
+			}
+			if len(matched) == 0 {
+				return silentExit(cmd, pickNoMatch)
+			}
+			return nil
+		}
+
+		if selectOne || exitZero {
+			matched := busybox.Filter(lines, formatter, query)
+			switch {
+			case len(matched) == 0 && exitZero:
+				return silentExit(cmd, pickNoMatch)
+			case len(matched) == 1 && selectOne:
+				fmt.Fprintln(out, matched[0])
+				return nil
+			}
+		}
+		if len(lines) == 0 {
+			return silentExit(cmd, pickNoMatch)
+		}
+
+		var picked []string
+		if multi {
+			picked, err = busybox.RunTermMulti(lines, formatter)
+		} else {
+			var line string
+			line, err = busybox.RunTermGrouped(lines, formatter)
+			picked = append(picked, line)
+		}
+		if errors.Is(err, busybox.ErrCancelled) {
+			return silentExit(cmd, pickInterrupted)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, line := range picked {
+			fmt.Fprintln(out, line)
+		}
+		return nil
+	},
+}
+
+// pickLayout returns the layout of the configuration with the layout flags
+// that were given applied over it
+func pickLayout(cmd *cobra.Command) (busybox.Layout, error) {
+	ui := config.Get().UI
+	layout := busybox.Layout{Height: ui.Height, Reverse: ui.Reverse, Border: ui.Border}
+
+	flags := cmd.Flags()
+	for name, value := range map[string]*string{
+		"height": &layout.Height,
+		"prompt": &layout.Prompt,
+		"header": &layout.Header,
+	} {
+		if !flags.Changed(name) {
+			continue
+		}
+		v, err := flags.GetString(name)
+		if err != nil {
+			return layout, fmt.Errorf("error getting %s flag: %w", name, err)
+		}
+		*value = v
+	}
+	for name, value := range map[string]*bool{
+		"reverse": &layout.Reverse,
+		"border":  &layout.Border,
+	} {
+		if !flags.Changed(name) {
+			continue
+		}
+		v, err := flags.GetBool(name)
+		if err != nil {
+			return layout, fmt.Errorf("error getting %s flag: %w", name, err)
+		}
+		*value = v
+	}
+	return layout, nil
+}
+
+// readLines returns the non-empty lines of r
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, s.Err()
+}
+
+func init() {
+	pickCmd.Flags().BoolP("multi", "m", false, "Allow marking several lines with Tab")
+	pickCmd.Flags().String("query", "", "Start the picker with this query")
+	pickCmd.Flags().StringP("filter", "f", "", "Print the lines matching the query without the picker")
+	pickCmd.Flags().BoolP("select-1", "1", false, "Print the only match of --query without the picker")
+	pickCmd.Flags().BoolP("exit-0", "0", false, "Exit without the picker when nothing matches --query")
+	pickCmd.Flags().String("history", "", "Recall queries and rank choices from the history kept under this name")
+	pickCmd.Flags().String("height", "", "Draw the picker inline in this many rows, or a share like 40%")
+	pickCmd.Flags().Bool("reverse", false, "Put the prompt at the bottom with the list above it")
+	pickCmd.Flags().Bool("border", false, "Draw a box around the picker")
+	pickCmd.Flags().String("prompt", "> ", "Prompt shown before the query")
+	pickCmd.Flags().String("header", "", "Lines shown between the prompt and the list")
+	rootCmd.AddCommand(pickCmd)
+}
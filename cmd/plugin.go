@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/DnFreddie/gosh/internal/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pluginPrefix         = "gosh-"
+	pluginCompletePrefix = "gosh_complete-"
+	pluginGroup          = "plugins"
+
+	// pluginDescriptionTag marks the line holding a plugin's short help, e.g.
+	//   # gosh-description: Sync the team dotfiles
+	pluginDescriptionTag = "gosh-description:"
+)
+
+// pluginDirs returns the directories searched for plugins in order of
+// precedence: the gosh plugins directory first, then $PATH.
+func pluginDirs() []string {
+	dirs := []string{filepath.Join(config.Dir(), "plugins")}
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// findExecutables returns the executables in dirs whose name starts with
+// prefix, keyed by the rest of the name. The first one found wins.
+func findExecutables(dirs []string, prefix string) map[string]string {
+	found := make(map[string]string)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), prefix)
+			if !ok || name == "" {
+				continue
+			}
+			if _, seen := found[name]; seen {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			found[name] = path
+		}
+	}
+	return found
+}
+
+// addPlugins registers every gosh-<name> executable as `gosh <name>`.
+// Built-in commands always take precedence over plugins.
+func addPlugins(root *cobra.Command) {
+	dirs := pluginDirs()
+	plugins := findExecutables(dirs, pluginPrefix)
+	if len(plugins) == 0 {
+		return
+	}
+	completers := findExecutables(dirs, pluginCompletePrefix)
+
+	root.AddGroup(&cobra.Group{ID: pluginGroup, Title: "Plugin Commands:"})
+	for name, path := range plugins {
+		if isBuiltin(root, name) {
+			continue
+		}
+		root.AddCommand(newPluginCmd(name, path, completers[name]))
+	}
+}
+
+// needsPlugins reports whether running root with args may involve plugins:
+// names that aren't built-in commands, help and their completion. Finding
+// plugins reads every gosh-* file on $PATH, built-in commands skip that.
+func needsPlugins(root *cobra.Command, args []string) bool {
+	if len(args) > 0 && (args[0] == cobra.ShellCompRequestCmd || args[0] == cobra.ShellCompNoDescRequestCmd) {
+		args = args[1:]
+	}
+	cmd, _, err := root.Find(args)
+	return err != nil || cmd == root
+}
+
+func isBuiltin(root *cobra.Command, name string) bool {
+	for _, c := range root.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return name == "help" || name == "completion"
+}
+
+func newPluginCmd(name, path, completer string) *cobra.Command {
+	short := pluginDescription(path)
+	if short == "" {
+		short = "Plugin " + path
+	}
+
+	return &cobra.Command{
+		Use:     name,
+		Short:   short,
+		GroupID: pluginGroup,
+		Long: fmt.Sprintf(`%s
+
+Provided by the plugin %s.
+All arguments and flags are passed on to it unchanged, run "gosh %s --help" for its own help.`, short, path, name),
+		DisableFlagParsing: true,
+		SilenceUsage:       true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := exec.CommandContext(cmd.Context(), path, args...)
+			c.Stdin = os.Stdin
+			c.Stdout = os.Stdout
+			c.Stderr = os.Stderr
+			c.Env = append(os.Environ(), pluginEnv(name)...)
+			err := c.Run()
+
+			// The plugin printed its own errors, only its status is passed on
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return silentExit(cmd, pluginExitCode(exitErr))
+			}
+			if err != nil {
+				return fmt.Errorf("plugin %s: %w", path, err)
+			}
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if completer == "" {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return completePlugin(completer, name, append(args, toComplete))
+		},
+	}
+}
+
+// pluginExitCode is the exit status of a plugin, 128 and the signal number
+// when it was killed
+func pluginExitCode(err *exec.ExitError) int {
+	if ws, ok := err.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return err.ExitCode()
+}
+
+// pluginEnv describes the calling gosh to a plugin. GOSH_CONFIG is only
+// set when a file was read, gosh run by the plugin fails on a missing one.
+func pluginEnv(name string) []string {
+	env := []string{
+		"GOSH_PLUGIN=" + name,
+		"GOSH_CONFIG_DIR=" + config.Dir(),
+	}
+	if cfg := config.Source(); cfg != "" {
+		env = append(env, "GOSH_CONFIG="+cfg)
+	}
+	if exe, err := os.Executable(); err == nil {
+		env = append(env, "GOSH_BIN="+exe)
+	}
+	if os.Getenv("TMUX") != "" {
+		out, err := exec.Command("tmux", "display-message", "-p", "#S").Output()
+		if err == nil {
+			env = append(env, "GOSH_TMUX_SESSION="+strings.TrimSpace(string(out)))
+		}
+	}
+	return env
+}
+
+// completePlugin runs the plugin's gosh_complete-<name> helper. It prints one
+// candidate per line and may end with ":<directive>" like cobra's __complete.
+func completePlugin(completer, name string, args []string) ([]string, cobra.ShellCompDirective) {
+	c := exec.Command(completer, args...)
+	c.Env = append(os.Environ(), pluginEnv(name)...)
+	out, err := c.Output()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	directive := cobra.ShellCompDirectiveNoFileComp
+	var candidates []string
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if d, ok := strings.CutPrefix(line, ":"); ok {
+			if n, err := strconv.Atoi(d); err == nil {
+				directive = cobra.ShellCompDirective(n)
+				continue
+			}
+		}
+		if line != "" {
+			candidates = append(candidates, line)
+		}
+	}
+	return candidates, directive
+}
+
+// pluginDescription looks for a gosh-description tag near the top of the file
+func pluginDescription(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(io.LimitReader(f, 4096))
+	for s.Scan() {
+		if _, desc, ok := strings.Cut(s.Text(), pluginDescriptionTag); ok {
+			return strings.TrimSpace(desc)
+		}
+	}
+	return ""
+}
@@ -4,15 +4,58 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 
+	"github.com/DnFreddie/gosh/internal/config"
+	"github.com/DnFreddie/gosh/pkg/busybox"
 	"github.com/spf13/cobra"
 )
 
+var (
+	cfgFile   string
+	verbosity int
+	quiet     bool
+	logFormat string
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "g",
 	Short: "Definietly not my toolbox",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// The arguments are fine from here on, errors don't need the usage
+		cmd.SilenceUsage = true
+		if err := setupLogging(os.Stderr); err != nil {
+			return err
+		}
+		if err := config.Init(cfgFile); err != nil {
+			return err
+		}
+		ui := config.Get().UI
+		theme, err := uiTheme(ui)
+		if err != nil {
+			return fmt.Errorf("config [ui]: %w", err)
+		}
+		err = busybox.Configure(busybox.Settings{
+			Mouse: ui.Mouse,
+			Layout: busybox.Layout{
+				Height:  ui.Height,
+				Reverse: ui.Reverse,
+				Border:  ui.Border,
+			},
+			Theme:  theme,
+			Colors: busybox.DetectColorMode(),
+		})
+		if err != nil {
+			return fmt.Errorf("config [ui.colors]: %w", err)
+		}
+		return nil
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -20,11 +63,128 @@ var rootCmd = &cobra.Command{
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+//
+// When the binary was started through one of the links made by `gosh link`
+// the name it was called with selects the command to run.
 func Execute() {
+	args := os.Args[1:]
+	if applet, ok := appletArgs(os.Args[0], args); ok {
+		args = applet
+		rootCmd.SetArgs(args)
+	}
+	if needsPlugins(rootCmd, args) {
+		addPlugins(rootCmd)
+	}
+
+	quietErrors(rootCmd)
 	err := rootCmd.Execute()
+	if err == nil {
+		return
+	}
+
+	// Signals exit with 128 and their number, other commands with their own status
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		os.Exit(exitErr.ExitCode())
+	}
+	var code exitCode
+	if errors.As(err, &code) {
+		os.Exit(int(code))
+	}
+	if errors.Is(err, busybox.ErrCancelled) {
+		os.Exit(exitCancelled)
+	}
+	os.Exit(1)
+}
+
+// exitCancelled is the exit status after leaving a picker with Escape or
+// Ctrl-C, the same as after SIGINT
+const exitCancelled = 130
+
+// exitCode is an error standing for an exit status that needs no message,
+// like pick finding no match
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+// silentExit makes cmd exit with code without printing an error or usage
+func silentExit(cmd *cobra.Command, code int) error {
+	cmd.SilenceUsage = true
+	return exitCode(code)
+}
+
+// quietErrors keeps cobra from printing the errors of cmd and the commands
+// under it that need no message: leaving a picker and exit statuses
+func quietErrors(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			err := run(cmd, args)
+			var code exitCode
+			if errors.Is(err, busybox.ErrCancelled) || errors.Is(err, busybox.ErrInterrupted) || errors.As(err, &code) {
+				cmd.SilenceErrors = true
+			}
+			return err
+		}
+	}
+	for _, c := range cmd.Commands() {
+		quietErrors(c)
+	}
+}
+
+// setupLogging installs the default slog handler according to the
+// -v/-q/--log-format flags. Diagnostics always go to w, never to stdout,
+// so the output of commands stays clean for piping.
+func setupLogging(w io.Writer) error {
+	level := slog.LevelInfo
+	switch {
+	case quiet:
+		level = slog.LevelError
+	case verbosity > 0:
+		level = slog.LevelDebug
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: verbosity > 1,
+	}
+
+	var handler slog.Handler
+	switch logFormat {
+	case "text":
+		// Timestamps are noise on an interactive terminal
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		}
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("unknown log format %q, expected text or json", logFormat)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// uiTheme returns the theme named in the [ui] section with the colors set
+// there replacing its own
+func uiTheme(ui config.UI) (busybox.Theme, error) {
+	theme, err := busybox.LookupTheme(ui.Theme)
 	if err != nil {
-		os.Exit(1)
+		return theme, err
 	}
+	theme.Prompt = cmp.Or(ui.Colors.Prompt, theme.Prompt)
+	theme.Pointer = cmp.Or(ui.Colors.Pointer, theme.Pointer)
+	theme.Marker = cmp.Or(ui.Colors.Marker, theme.Marker)
+	theme.Match = cmp.Or(ui.Colors.Match, theme.Match)
+	theme.Muted = cmp.Or(ui.Colors.Muted, theme.Muted)
+	theme.Error = cmp.Or(ui.Colors.Error, theme.Error)
+	return theme, nil
 }
 
 func init() {
@@ -32,7 +192,11 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.sessionizer.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $XDG_CONFIG_HOME/gosh/config.toml)")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Log debug messages, twice to add source locations")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only log errors")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format on stderr (text, json)")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// buildTime can be stamped at link time:
+//
+//	go build -ldflags "-X github.com/DnFreddie/gosh/cmd.buildTime=$(date -u +%FT%TZ)"
+//
+// Otherwise the commit time recorded by the go tool is reported.
+var buildTime string
+
+// externalTools are the programs gosh drives, with the flag printing their version
+var externalTools = [][]string{
+	{"tmux", "-V"},
+	{"git", "--version"},
+	{"vim", "--version"},
+}
+
+type toolVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type versionInfo struct {
+	Version   string        `json:"version"`
+	Revision  string        `json:"revision,omitempty"`
+	Dirty     bool          `json:"dirty"`
+	BuildTime string        `json:"build_time,omitempty"`
+	GoVersion string        `json:"go_version"`
+	Chroma    string        `json:"chroma,omitempty"`
+	Tools     []toolVersion `json:"tools"`
+}
+
+var versionCmd = &cobra.Command{
+	Use:          "version",
+	Short:        "Print build information and the versions of the tools gosh drives",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			return fmt.Errorf("error getting json flag: %w", err)
+		}
+
+		info := buildInfo()
+		for _, tool := range externalTools {
+			info.Tools = append(info.Tools, toolVersion{
+				Name:    tool[0],
+				Version: lookupToolVersion(cmd.Context(), tool[0], tool[1:]...),
+			})
+		}
+
+		out := cmd.OutOrStdout()
+		if asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(info)
+		}
+
+		fmt.Fprintf(out, "gosh %s\n", info.Version)
+		if info.Revision != "" {
+			dirty := ""
+			if info.Dirty {
+				dirty = " (dirty)"
+			}
+			fmt.Fprintf(out, "  revision:   %s%s\n", info.Revision, dirty)
+		}
+		if info.BuildTime != "" {
+			fmt.Fprintf(out, "  built:      %s\n", info.BuildTime)
+		}
+		fmt.Fprintf(out, "  go:         %s\n", info.GoVersion)
+		if info.Chroma != "" {
+			fmt.Fprintf(out, "  chroma:     %s\n", info.Chroma)
+		}
+		for _, t := range info.Tools {
+			fmt.Fprintf(out, "  %-11s %s\n", t.Name+":", t.Version)
+		}
+		return nil
+	},
+}
+
+// buildInfo reads the metadata the go tool embeds in the binary
+func buildInfo() versionInfo {
+	info := versionInfo{
+		Version:   "(devel)",
+		BuildTime: buildTime,
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	info.GoVersion = bi.GoVersion
+	if bi.Main.Version != "" {
+		info.Version = bi.Main.Version
+	}
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.modified":
+			info.Dirty = s.Value == "true"
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = s.Value
+			}
+		}
+	}
+
+	for _, dep := range bi.Deps {
+		if dep.Path != "github.com/alecthomas/chroma" {
+			continue
+		}
+		info.Chroma = dep.Version
+		if dep.Replace != nil {
+			info.Chroma = dep.Replace.Version
+		}
+	}
+	return info
+}
+
+// lookupToolVersion returns the first line printed by name with args,
+// or "not found" when the tool isn't installed.
+func lookupToolVersion(ctx context.Context, name string, args ...string) string {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "not found"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	if err != nil {
+		return fmt.Sprintf("unknown (%v)", err)
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+	if s.Scan() {
+		return strings.TrimSpace(s.Text())
+	}
+	return "unknown"
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().Bool("json", false, "Print the information as JSON")
+}
@@ -3,8 +3,9 @@ module github.com/DnFreddie/gosh
 go 1.23.0
 
 require (
-	github.com/DnFreddie/goseq v0.0.0-20241009195533-695a8700fb65
+	github.com/BurntSushi/toml v1.4.0
 	github.com/alecthomas/chroma v0.10.0
+	github.com/mattn/go-runewidth v0.0.16
 	github.com/rogpeppe/go-internal v1.13.1
 	github.com/spf13/cobra v1.8.1
 	golang.org/x/term v0.24.0
@@ -13,6 +14,7 @@ require (
 require (
 	github.com/dlclark/regexp2 v1.11.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.25.0 // indirect
 )
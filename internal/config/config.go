@@ -0,0 +1,296 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config holds the effective gosh settings, one section per command.
+//
+// Values come from the defaults, then ~/.config/gosh/config.toml, then the
+// GOSH_<SECTION>_<KEY> environment variables, in that order.
+type Config struct {
+	Sessionizer Sessionizer `toml:"sessionizer"`
+	Install     Install     `toml:"install"`
+	Snip        Snip        `toml:"snip"`
+	Cat         Cat         `toml:"cat"`
+	Edit        Edit        `toml:"edit"`
+	UI          UI          `toml:"ui"`
+}
+
+// Sessionizer configures the `s` and `tn` commands
+type Sessionizer struct {
+	Skip       []string `toml:"skip" env:"GOSH_SESSIONIZER_SKIP"`               // Directory names never descended into
+	Depth      int      `toml:"depth" env:"GOSH_SESSIONIZER_DEPTH"`             // How deep Find walks below $HOME
+	ReposDir   string   `toml:"repos_dir" env:"GOSH_SESSIONIZER_REPOS_DIR"`     // Where `s fg` clones repositories
+	GithubUser string   `toml:"github_user" env:"GOSH_SESSIONIZER_GITHUB_USER"` // Whose repositories `s fg` lists
+}
+
+// Install configures the `install` command and its subcommands
+type Install struct {
+	TargetDir     string   `toml:"target_dir" env:"GOSH_INSTALL_TARGET_DIR"`
+	TempDir       string   `toml:"temp_dir" env:"GOSH_INSTALL_TEMP_DIR"`
+	CompletionDir string   `toml:"completion_dir" env:"GOSH_INSTALL_COMPLETION_DIR"`
+	Shell         string   `toml:"shell" env:"GOSH_INSTALL_SHELL"`
+	Toolbox       []string `toml:"toolbox" env:"GOSH_INSTALL_TOOLBOX"`
+}
+
+// Snip configures the `install snip` command
+type Snip struct {
+	Dir string `toml:"dir" env:"GOSH_SNIP_DIR"` // Directory holding the *.md snippet files
+}
+
+// Cat configures the `cat` pager
+type Cat struct {
+	Style     string `toml:"style" env:"GOSH_CAT_STYLE"`           // Chroma style used in the pager, empty for the one of the theme
+	PipeStyle string `toml:"pipe_style" env:"GOSH_CAT_PIPE_STYLE"` // Chroma style used when stdout is not a terminal, empty for the one of the theme
+}
+
+// UI configures the picker and the pager of every command
+type UI struct {
+	Mouse   bool   `toml:"mouse" env:"GOSH_UI_MOUSE"`     // Clicks select and the wheel scrolls
+	Height  string `toml:"height" env:"GOSH_UI_HEIGHT"`   // Rows of the picker like "15" or "40%", empty for the whole screen
+	Reverse bool   `toml:"reverse" env:"GOSH_UI_REVERSE"` // Prompt at the bottom of the picker
+	Border  bool   `toml:"border" env:"GOSH_UI_BORDER"`   // Box around the picker
+	Theme   string `toml:"theme" env:"GOSH_UI_THEME"`     // Named colors of the picker and chroma style
+	Colors  Colors `toml:"colors"`                        // Colors replacing those of the theme
+}
+
+// Colors overrides single colors of the theme, empty keeps the theme's.
+// Colors are names like "cyan", 256 color indexes like "208" or "#rrggbb",
+// optionally after "bold" or "underline".
+type Colors struct {
+	Prompt  string `toml:"prompt" env:"GOSH_UI_COLORS_PROMPT"`
+	Pointer string `toml:"pointer" env:"GOSH_UI_COLORS_POINTER"`
+	Marker  string `toml:"marker" env:"GOSH_UI_COLORS_MARKER"`
+	Match   string `toml:"match" env:"GOSH_UI_COLORS_MATCH"`
+	Muted   string `toml:"muted" env:"GOSH_UI_COLORS_MUTED"`
+	Error   string `toml:"error" env:"GOSH_UI_COLORS_ERROR"`
+}
+
+// Edit configures the `edit` command
+type Edit struct {
+	Editor string `toml:"editor" env:"GOSH_EDIT_EDITOR"` // Command with its arguments like "code --wait", empty means vim, falling back to vi
+	Vimrc  string `toml:"vimrc" env:"GOSH_EDIT_VIMRC"`   // Empty means the vimrc embedded in the binary
+}
+
+var (
+	current = Default()
+	source  string
+)
+
+// Get returns the configuration loaded by [Init], or the defaults
+// if nothing was loaded yet.
+func Get() *Config {
+	return current
+}
+
+// Source returns the file the current configuration was read from,
+// or an empty string when only defaults and the environment were used.
+func Source() string {
+	return source
+}
+
+// Init loads the configuration from path (see [Load]) and makes it
+// the one returned by [Get].
+func Init(path string) error {
+	cfg, from, err := Load(path)
+	if err != nil {
+		return err
+	}
+	current, source = cfg, from
+	return nil
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	home, _ := os.UserHomeDir()
+	return &Config{
+		Sessionizer: Sessionizer{
+			Skip:       []string{".cache", ".local", "node_modules", ".git"},
+			Depth:      3,
+			ReposDir:   filepath.Join(home, "github.com"),
+			GithubUser: "DnFreddie",
+		},
+		Install: Install{
+			TargetDir:     filepath.Join(home, ".local", "bin"),
+			TempDir:       os.TempDir(),
+			CompletionDir: filepath.Join(home, ".local", "share", "completions"),
+			Shell:         "bash",
+			Toolbox: []string{
+				"cli/cli",
+				"mikefarah/yq",
+				"junegunn/fzf",
+			},
+		},
+		Snip: Snip{
+			Dir: filepath.Join(home, ".dotfiles", "snippets"),
+		},
+		UI: UI{
+			Mouse: true,
+			Theme: "default",
+		},
+	}
+}
+
+// Dir returns the gosh configuration directory,
+// $XDG_CONFIG_HOME/gosh or ~/.config/gosh.
+func Dir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gosh")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gosh")
+}
+
+// Path returns the configuration file used when none is given explicitly.
+// GOSH_CONFIG takes precedence over [Dir]/config.toml.
+func Path() string {
+	if p := os.Getenv("GOSH_CONFIG"); p != "" {
+		return p
+	}
+	return filepath.Join(Dir(), "config.toml")
+}
+
+// Load builds a configuration from the defaults, the file at path and the
+// environment. An empty path means [Path]. Only the default file is allowed
+// to not exist, one given by path or GOSH_CONFIG has to.
+// It also returns the file that was actually read, if any.
+func Load(path string) (*Config, string, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("GOSH_CONFIG")
+	}
+	explicit := path != ""
+	if !explicit {
+		path = Path()
+	}
+
+	from := ""
+	f, err := os.Open(path)
+	switch {
+	case err == nil:
+		defer f.Close()
+		if err := Decode(f, cfg); err != nil {
+			return nil, "", fmt.Errorf("config %s: %w", path, err)
+		}
+		from = path
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+	default:
+		return nil, "", fmt.Errorf("opening config: %w", err)
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, "", err
+	}
+	cfg.expandPaths()
+
+	return cfg, from, nil
+}
+
+// Decode reads TOML from r on top of the values already in cfg.
+// Keys gosh doesn't know about are reported as an error.
+func Decode(r io.Reader, cfg *Config) error {
+	md, err := toml.NewDecoder(r).Decode(cfg)
+	if err != nil {
+		return err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		return fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// Write prints cfg as TOML
+func (c *Config) Write(w io.Writer) error {
+	return toml.NewEncoder(w).Encode(c)
+}
+
+func (c *Config) expandPaths() {
+	for _, p := range []*string{
+		&c.Sessionizer.ReposDir,
+		&c.Install.TargetDir,
+		&c.Install.TempDir,
+		&c.Install.CompletionDir,
+		&c.Snip.Dir,
+		&c.Edit.Vimrc,
+	} {
+		*p = ExpandPath(*p)
+	}
+}
+
+// ExpandPath expands a leading ~ and any environment variables in p
+func ExpandPath(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = home + p[1:]
+		}
+	}
+	return os.ExpandEnv(p)
+}
+
+// applyEnv overrides the fields of v tagged with `env` from the environment.
+// Lists are comma separated.
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			value.SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			value.SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			value.SetBool(b)
+		case reflect.Slice:
+			var list []string
+			for _, s := range strings.Split(raw, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					list = append(list, s)
+				}
+			}
+			value.Set(reflect.ValueOf(list))
+		default:
+			return fmt.Errorf("%s: unsupported type %s", name, field.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// [Decode] only overrides the keys present in the file
+func ExampleDecode() {
+	cfg := Default()
+	file := `
+[sessionizer]
+depth = 5
+skip = [".git", "vendor"]
+
+[cat]
+style = "dracula"
+`
+	if err := Decode(strings.NewReader(file), cfg); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	fmt.Println("Depth:", cfg.Sessionizer.Depth)
+	fmt.Println("Skip:", cfg.Sessionizer.Skip)
+	fmt.Println("Style:", cfg.Cat.Style)
+	fmt.Printf("PipeStyle: %q\n", cfg.Cat.PipeStyle)
+	// Output:
+	// Depth: 5
+	// Skip: [.git vendor]
+	// Style: dracula
+	// PipeStyle: ""
+}
+
+// [Decode] rejects keys that don't exist so typos don't go unnoticed
+func ExampleDecode_unknownKey() {
+	err := Decode(strings.NewReader("[snip]\ndirr = \"/tmp\"\n"), Default())
+	fmt.Println(err)
+	// Output:
+	// unknown keys: snip.dirr
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	content := "[install]\ntarget_dir = \"~/bin\"\ntoolbox = [\"cli/cli\"]\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	t.Setenv("HOME", dir)
+	t.Setenv("GOSH_INSTALL_TOOLBOX", "junegunn/fzf, mikefarah/yq")
+	t.Setenv("GOSH_SESSIONIZER_DEPTH", "2")
+
+	cfg, from, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	if from != path {
+		t.Errorf("Expected source %s, got %s", path, from)
+	}
+	if want := filepath.Join(dir, "bin"); cfg.Install.TargetDir != want {
+		t.Errorf("Expected target dir %s, got %s", want, cfg.Install.TargetDir)
+	}
+	if got := strings.Join(cfg.Install.Toolbox, " "); got != "junegunn/fzf mikefarah/yq" {
+		t.Errorf("Expected toolbox from the environment, got %s", got)
+	}
+	if cfg.Sessionizer.Depth != 2 {
+		t.Errorf("Expected depth 2, got %d", cfg.Sessionizer.Depth)
+	}
+
+	t.Run("Missing default file", func(t *testing.T) {
+		t.Setenv("GOSH_CONFIG", "")
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "missing"))
+		if _, from, err := Load(""); err != nil || from != "" {
+			t.Errorf("Expected no error and no source for a missing default config, got %q, %v", from, err)
+		}
+	})
+
+	t.Run("Missing GOSH_CONFIG file", func(t *testing.T) {
+		t.Setenv("GOSH_CONFIG", filepath.Join(dir, "missing.toml"))
+		if _, _, err := Load(""); err == nil {
+			t.Error("Expected error for a missing config named by GOSH_CONFIG, got none")
+		}
+	})
+
+	t.Run("Missing explicit file", func(t *testing.T) {
+		if _, _, err := Load(filepath.Join(dir, "missing.toml")); err == nil {
+			t.Error("Expected error for a missing explicit config, got none")
+		}
+	})
+
+	t.Run("Invalid env value", func(t *testing.T) {
+		t.Setenv("GOSH_SESSIONIZER_DEPTH", "deep")
+		if _, _, err := Load(path); err == nil {
+			t.Error("Expected error for a non numeric depth, got none")
+		}
+	})
+}
@@ -5,44 +5,57 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/DnFreddie/gosh/internal/config"
 	"github.com/DnFreddie/gosh/scripts"
 )
 
-func Edit(toEdit string) {
+// Edit opens toEdit in the configured editor, vim or vi by default,
+// using the embedded vimrc unless cfg points at another one.
+// The editor may come with arguments, like "code --wait".
+func Edit(toEdit string, cfg config.Edit) error {
 
-	var editor = "vi"
-	if _, err := exec.LookPath("vim"); err == nil {
-		editor = "vim"
-	}
-
-	vimrcPath := filepath.Join(os.TempDir(), "embedded_vimrc")
-	if _, err := os.Stat(vimrcPath); os.IsNotExist(err) {
-		vimrcContent, err := scripts.Embeded.ReadFile("vimrc")
-		if err != nil {
-			fmt.Printf("Failed to read vimrc file: %v\n", err)
-			return
+	args := strings.Fields(cfg.Editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+		if _, err := exec.LookPath("vim"); err == nil {
+			args = []string{"vim"}
 		}
-		err = os.WriteFile(vimrcPath, vimrcContent, 0644)
-		if err != nil {
-			fmt.Printf("Failed to write vimrc file: %v\n", err)
-			return
+	}
+	editor, args := args[0], args[1:]
+
+	vimrcPath := cfg.Vimrc
+	if vimrcPath == "" {
+		vimrcPath = filepath.Join(os.TempDir(), "embedded_vimrc")
+		if _, err := os.Stat(vimrcPath); os.IsNotExist(err) {
+			vimrcContent, err := scripts.Embeded.ReadFile("vimrc")
+			if err != nil {
+				return fmt.Errorf("failed to read vimrc file: %w", err)
+			}
+			err = os.WriteFile(vimrcPath, vimrcContent, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to write vimrc file: %w", err)
+			}
 		}
 	}
 
-	var cmd *exec.Cmd
-
-	switch editor {
-	case "vim":
-		cmd = exec.Command(editor, "-N", "-u", vimrcPath, toEdit)
-	case "vi":
-		cmd = exec.Command(editor, "-u", vimrcPath, toEdit)
+	// A vimrc given in the configured command wins over ours
+	switch base := filepath.Base(editor); {
+	case slices.Contains(args, "-u"):
+	case base == "vim" || base == "nvim":
+		args = append(args, "-N", "-u", vimrcPath)
+	case base == "vi":
+		args = append(args, "-u", vimrcPath)
 	}
+	cmd := exec.Command(editor, append(args, toEdit)...)
 
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
-		fmt.Printf("Failed to run %s: %v\n", editor, err)
+		return fmt.Errorf("failed to run %s: %w", editor, err)
 	}
+	return nil
 }
@@ -7,12 +7,20 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var _prune bool // Drop the directories that no longer exist from the ranking
+
 // fdCmd represents the fd command
 var FdCmd = &cobra.Command{
-	Use:          "fd",
-	Short:        "List all dirs in home and lest u create the session else switches to one",
+	Use:   "fd",
+	Short: "List all dirs in home and lest u create the session else switches to one",
+	Long: `Lists the directories in home, the ones picked most and most recently first, and creates
+a session for the chosen one or switches to it. --prune drops the directories that no longer
+exist from the ranking and prints them.`,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if _prune {
+			return PruneDirs(cmd.OutOrStdout())
+		}
 		if err := Fd(); err != nil {
 
 			return err
@@ -22,6 +30,7 @@ var FdCmd = &cobra.Command{
 }
 
 func init() {
+	FdCmd.Flags().BoolVar(&_prune, "prune", false, "Drop directories that no longer exist from the ranking")
 
 	// Here you will define your flags and configuration settings.
 
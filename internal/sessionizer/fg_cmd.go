@@ -3,8 +3,8 @@ package sessionizer
 import (
 	"errors"
 	"fmt"
-	"os"
 
+	"github.com/DnFreddie/gosh/internal/config"
 	"github.com/DnFreddie/gosh/pkg/github"
 	"github.com/spf13/cobra"
 )
@@ -12,22 +12,23 @@ import (
 var (
 	_git_url  string // GitHub repository URL to clone
 	_reposDir string // Directory where repositories are stored
+	_multi    bool   // Pick several repositories at once
 )
 
 var FgCmd = &cobra.Command{
 	Use:   "fg",
 	Short: "Select a GitHub repo, clone it if absent, and create a session",
-	Long: `Uses the GitHub API to list available repositories. If the repository does not exist in
-~/HOME/github.com/DnFreddie, it will be cloned and a tmux session created. Otherwise, it switches to an existing session.`,
+	Long: `Uses the GitHub API to list the repositories of sessionizer.github_user. If the repository does not exist in
+sessionizer.repos_dir (~/github.com by default), it will be cloned and a tmux session created. Otherwise, it switches to an existing session.`,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if _reposDir == "" {
-			_reposDir = os.ExpandEnv("${HOME}/github.com")
+			_reposDir = config.Get().Sessionizer.ReposDir
 		}
 		if _git_url != "" {
 			return cloneAndTmux(_git_url, _reposDir)
 		}
-		if err := Fg(_reposDir); err != nil {
+		if err := Fg(_reposDir, _multi); err != nil {
 			return fmt.Errorf("error in Fg operation: %w", err)
 		}
 		return nil
@@ -37,6 +38,7 @@ var FgCmd = &cobra.Command{
 func init() {
 	FgCmd.Flags().StringVarP(&_git_url, "url", "u", "", "URL to clone and create session for")
 	FgCmd.Flags().StringVarP(&_reposDir, "path", "p", "", "Directory where the repository is stored")
+	FgCmd.Flags().BoolVarP(&_multi, "multi", "m", false, "Mark several repositories with Tab and clone them all")
 }
 
 func cloneAndTmux(url, reposDir string) error {
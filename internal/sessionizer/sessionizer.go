@@ -2,9 +2,12 @@ package sessionizer
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"log/slog"
 	"net/http"
 	"os"
 	"path"
@@ -12,9 +15,10 @@ import (
 	"regexp"
 	"slices"
 	"strings"
-	"sync"
 
+	"github.com/DnFreddie/gosh/internal/config"
 	"github.com/DnFreddie/gosh/pkg/busybox"
+	"github.com/DnFreddie/gosh/pkg/frecency"
 	"github.com/DnFreddie/gosh/pkg/github"
 )
 
@@ -41,8 +45,9 @@ func Fs() error {
 		return err
 	}
 
-	choice, err := busybox.RunTerm(hosts, func(h Host) string {
-		return fmt.Sprintf("%s", h.Name)
+	choice, err := busybox.RunTermGrouped(hosts, busybox.ItemFormatter[Host]{
+		ToString: func(h Host) string { return h.Name },
+		History:  "fs",
 	})
 	if err != nil {
 		return err
@@ -105,28 +110,33 @@ func Fd() error {
 		return errors.New("Failed to find HOMEDIR")
 	}
 
-	dirs, err := Find(home)
+	cfg := config.Get().Sessionizer
+	store := openFrecency(dirsStore)
+	dirs := frecentFirst(store, home, FindSeq(home, cfg.Skip, cfg.Depth))
+
+	res, err := busybox.Pick(dirs, busybox.ItemFormatter[string]{
+		ToString: func(s string) string {
+			return strings.Replace(s, home, "", 1)
+		},
+		Preview: previewDir,
+		Actions: map[busybox.Key]busybox.Action[string]{
+			busybox.CtrlV: {Name: "edit"},
+		},
+		History: "fd",
+	}, false)
 	if err != nil {
-		return err
-	}
-
-	var display []string
-	for _, v := range dirs {
-		shorten_path := strings.Replace(v, home, "", 1)
-		display = append(display, shorten_path)
+		return pickFailed("directory not found or not selected", err)
 	}
 
-	choice, err := busybox.RunTerm(display, func(s string) string {
-		return s
-	})
-	if err != nil {
-		return fmt.Errorf("Directory not found or not selected: %v\n", err)
+	choice := res.Items[0]
+	remember(store, choice)
+	if res.Action == "edit" {
+		return openEditor(tmux, choice)
 	}
 
-	absChoice := path.Join(home, choice)
 	sessionName := path.Base(choice)
 
-	err = tmux.CreateSession(sessionName, absChoice)
+	err = tmux.CreateSession(sessionName, choice)
 	if err != nil {
 		return err
 	}
@@ -144,36 +154,234 @@ func Vf() error {
 		return errors.New("Failed to find HOMEDIR")
 	}
 
-	dirs, err := Find(home)
+	cfg := config.Get().Sessionizer
+	store := openFrecency(dirsStore)
+	dirs := frecentFirst(store, home, FindSeq(home, cfg.Skip, cfg.Depth))
+
+	choice, err := busybox.RunTermSeq(dirs, busybox.ItemFormatter[string]{
+		ToString: func(s string) string { return s },
+		Preview:  previewDir,
+		History:  "vf",
+	})
+	if err != nil {
+		return pickFailed("directory not found or not selected", err)
+	}
+
+	remember(store, choice)
+	return openEditor(tmux, choice)
+}
+
+// Names of the frecency stores, fd and vf share the directories
+const (
+	dirsStore     = "dirs"
+	sessionsStore = "sessions"
+)
+
+// openFrecency loads the frecency store kept under name in the state
+// directory. A store that can't be read is logged and starts empty.
+func openFrecency(name string) *frecency.Store {
+	store, err := frecency.Load(frecencyPath(name))
+	if err != nil {
+		slog.Debug("Failed to load the frecency store", "name", name, "error", err)
+	}
+	return store
+}
+
+func frecencyPath(name string) string {
+	return filepath.Join(busybox.StateDir(), "frecency", name+".json")
+}
+
+// remember records that key was chosen and saves store
+func remember(store *frecency.Store, key string) {
+	store.Add(key)
+	saveFrecency(store)
+}
+
+// saveFrecency saves store, a store that can't be saved is only logged
+func saveFrecency(store *frecency.Store) {
+	if err := store.Save(); err != nil {
+		slog.Debug("Failed to save the frecency store", "error", err)
+	}
+}
+
+// frecentFirst yields the directories below home chosen before, the most
+// frecent first, and then the rest of dirs
+func frecentFirst(store *frecency.Store, home string, dirs iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		seen := make(map[string]bool)
+		for _, dir := range store.Keys() {
+			if !strings.HasPrefix(dir, home+string(os.PathSeparator)) || !dirExists(dir) {
+				continue
+			}
+			seen[dir] = true
+			if !yield(dir) {
+				return
+			}
+		}
+		for dir := range dirs {
+			if !seen[dir] && !yield(dir) {
+				return
+			}
+		}
+	}
+}
+
+// PruneDirs drops the directories that no longer exist from the ranking of
+// fd and vf and prints them to w
+func PruneDirs(w io.Writer) error {
+	store, err := frecency.Load(frecencyPath(dirsStore))
 	if err != nil {
 		return err
 	}
+	for _, dir := range store.Prune(dirExists) {
+		fmt.Fprintln(w, dir)
+	}
+	return store.Save()
+}
 
-	choice, err := busybox.RunTerm(dirs, func(s string) string { return s })
+// dirExists reports whether path is a directory. One that can't be looked
+// at, for lack of permissions, still counts.
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("Directory not found or not selected: %v\n", err)
+		return !errors.Is(err, os.ErrNotExist)
 	}
+	return info.IsDir()
+}
 
-	if _, err := tmux.Run("new-window", "-c", choice, "$EDITOR ."); err != nil {
+// pickFailed adds what was picked to an error of the picker. Leaving the
+// picker is returned as it is, the exit status tells how it was left.
+func pickFailed(what string, err error) error {
+	if errors.Is(err, busybox.ErrCancelled) || errors.Is(err, busybox.ErrInterrupted) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", what, err)
+}
+
+// openEditor opens dir in $EDITOR in a new window of the current session
+func openEditor(tmux *Tmux, dir string) error {
+	if _, err := tmux.Run("new-window", "-c", dir, "$EDITOR ."); err != nil {
 		return err
 	}
 	return nil
 }
 
-func Find(dir string) ([]string, error) {
-	toSkip := []string{".cache", ".local", "node_modules", ".git"}
+// previewMaxEntries caps the directory listing shown in the preview
+const previewMaxEntries = 200
+
+// previewBatch is how many entries previewDir reads before it checks whether
+// the preview is still wanted
+const previewBatch = 256
+
+// previewDir lists the entries of dir, directories first and marked with a
+// trailing slash. It gives up once ctx is done.
+func previewDir(ctx context.Context, dir string) string {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err.Error()
+	}
+	defer f.Close()
+
+	var entries []os.DirEntry
+	for {
+		batch, err := f.ReadDir(previewBatch)
+		entries = append(entries, batch...)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err.Error()
+		}
+		if ctx.Err() != nil {
+			return ""
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b os.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	slices.SortStableFunc(entries, func(a, b os.DirEntry) int {
+		switch {
+		case a.IsDir() && !b.IsDir():
+			return -1
+		case !a.IsDir() && b.IsDir():
+			return 1
+		}
+		return 0
+	})
+
+	var sb strings.Builder
+	for i, e := range entries {
+		if i == previewMaxEntries {
+			fmt.Fprintf(&sb, "... %d more\n", len(entries)-i)
+			break
+		}
+		if e.IsDir() {
+			sb.WriteString(busybox.InColors(busybox.BrightBlue, e.Name()+"/"))
+		} else {
+			sb.WriteString(e.Name())
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Find returns the absolute paths of the directories below dir, at most depth
+// levels deep, without descending into directories named in toSkip.
+func Find(dir string, toSkip []string, depth int) ([]string, error) {
 	var absolutePaths []string
 	var errorsArr []error
-	var errorsMutex sync.Mutex
-	var pathsMutex sync.Mutex
-	buffered := make(chan struct{}, 5)
-	var wg sync.WaitGroup
 
-	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+	err := walkDirs(dir, toSkip, depth, func(path string, err error) bool {
+		if err != nil {
+			errorsArr = append(errorsArr, err)
+		} else {
+			absolutePaths = append(absolutePaths, path)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(errorsArr) > 0 {
+		return nil, errors.Join(errorsArr...)
+	}
+
+	return absolutePaths, nil
+}
+
+// FindSeq yields the directories [Find] returns as the walk finds them.
+// Directories that can't be read are logged and skipped.
+func FindSeq(dir string, toSkip []string, depth int) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		err := walkDirs(dir, toSkip, depth, func(path string, err error) bool {
+			if err != nil {
+				slog.Debug("Skipping directory", "error", err)
+				return true
+			}
+			return yield(path)
+		})
+		if err != nil {
+			slog.Debug("Finding directories failed", "dir", dir, "error", err)
+		}
+	}
+}
+
+// walkDirs calls fn with the absolute path of every directory below dir, at
+// most depth levels deep, skipping the ones named in toSkip. Paths that can't
+// be read are passed as errors. The walk stops when fn returns false.
+func walkDirs(dir string, toSkip []string, depth int, fn func(path string, err error) bool) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("error getting absolute path for %s: %w", dir, err)
+	}
+
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
-			errorsMutex.Lock()
-			errorsArr = append(errorsArr, fmt.Errorf("error accessing path %s: %w", path, err))
-			errorsMutex.Unlock()
+			if !fn("", fmt.Errorf("error accessing path %s: %w", path, err)) {
+				return filepath.SkipAll
+			}
 			return nil
 		}
 
@@ -185,69 +393,81 @@ func Find(dir string) ([]string, error) {
 			return filepath.SkipDir
 		}
 
-		relPath, err := filepath.Rel(dir, path)
+		relPath, err := filepath.Rel(root, path)
 		if err != nil {
-			errorsMutex.Lock()
-			errorsArr = append(errorsArr, fmt.Errorf("error getting relative path for %s: %w", path, err))
-			errorsMutex.Unlock()
+			if !fn("", fmt.Errorf("error getting relative path for %s: %w", path, err)) {
+				return filepath.SkipAll
+			}
 			return nil
 		}
 
-		depth := 1
+		level := 1
 		if relPath != "." {
-			depth = strings.Count(relPath, string(os.PathSeparator)) + 1
+			level = strings.Count(relPath, string(os.PathSeparator)) + 1
 		}
-		if depth > 3 {
+		if level > depth {
 			return filepath.SkipDir
 		}
 
-		buffered <- struct{}{}
-		wg.Add(1)
-		go func(p string) {
-			defer wg.Done()
-			defer func() { <-buffered }()
-
-			absPath, err := filepath.Abs(p)
-			if err != nil {
-				errorsMutex.Lock()
-				errorsArr = append(errorsArr, fmt.Errorf("error getting absolute path for %s: %w", p, err))
-				errorsMutex.Unlock()
-				return
-			}
-
-			pathsMutex.Lock()
-			absolutePaths = append(absolutePaths, absPath)
-			pathsMutex.Unlock()
-		}(path)
+		if !fn(path, nil) {
+			return filepath.SkipAll
+		}
 		return nil
 	})
-
 	if err != nil {
-		return nil, fmt.Errorf("error walking the path %s: %w", dir, err)
-	}
-
-	wg.Wait()
-
-	if len(errorsArr) > 0 {
-		return nil, errors.Join(errorsArr...)
+		return fmt.Errorf("error walking the path %s: %w", dir, err)
 	}
-
-	return absolutePaths, nil
+	return nil
 }
 
-func Fg(gitDir string) error {
-	rm, err := github.NewRepoManager(github.USER_REPOS, &http.Client{})
-	if err != nil {
-		return fmt.Errorf("failed to initialize RepoManager: %w", err)
+// Fg lets the user pick repositories of the configured GitHub user, clones
+// the missing ones into gitDir and switches to a session for the last one.
+// With multi several repositories can be marked, each gets its own session.
+func Fg(gitDir string, multi bool) error {
+	user := config.Get().Sessionizer.GithubUser
+
+	// The picker shows the repositories page by page. An error from GitHub
+	// fails the command only when nothing of what was loaded got picked.
+	fetchErr := make(chan error, 1)
+	source := func(yield func(github.Repo) bool) {
+		for repo, err := range github.FetchRepos(github.UserRepos(user), &http.Client{}) {
+			if err != nil {
+				fetchErr <- err
+				return
+			}
+			if !yield(repo) {
+				return
+			}
+		}
 	}
 
-	repo, err := busybox.RunTerm(rm.Repos, func(r github.Repo) string { return r.Name })
+	formatter := busybox.ItemFormatter[github.Repo]{
+		ToString: func(r github.Repo) string { return r.Name },
+		Preview:  previewRepo,
+		History:  "fg",
+	}
+	var repos []github.Repo
+	var err error
+	if multi {
+		repos, err = busybox.RunTermSeqMulti(source, formatter)
+	} else {
+		var repo github.Repo
+		repo, err = busybox.RunTermSeq(source, formatter)
+		repos = append(repos, repo)
+	}
+	var errFetch error
+	select {
+	case errFetch = <-fetchErr:
+	default:
+	}
+	if errFetch != nil && (err != nil || len(repos) == 0) {
+		return fmt.Errorf("failed to fetch repositories: %w", errFetch)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to run terminal command: %w", err)
+		return pickFailed("failed to run terminal command", err)
 	}
-
-	if err = repo.Clone(gitDir); err != nil && !errors.Is(err, github.RepoExistErr{}) {
-		return fmt.Errorf("failed to clone repository: %w", err)
+	if errFetch != nil {
+		slog.Warn("failed to fetch all repositories", "error", errFetch)
 	}
 
 	t, err := NewTmux()
@@ -255,24 +475,89 @@ func Fg(gitDir string) error {
 		return fmt.Errorf("failed to create new Tmux: %w", err)
 	}
 
-	if err := t.CreateSession(repo.Name, repo.Path); err != nil {
-		return fmt.Errorf("failed to create Tmux session: %w", err)
+	last := ""
+	for _, repo := range repos {
+		if err = repo.Clone(gitDir); err != nil && !errors.Is(err, github.RepoExistErr{}) {
+			return fmt.Errorf("failed to clone repository: %w", err)
+		}
+
+		if last, err = t.NewSession(repo.Name, repo.Path); err != nil {
+			return fmt.Errorf("failed to create Tmux session: %w", err)
+		}
 	}
 
-	return nil
+	return t.SwitchSession(last)
 }
 
-func (t *Tmux) Tn() error {
+// previewRepo shows what GitHub knows about r
+func previewRepo(_ context.Context, r github.Repo) string {
+	var sb strings.Builder
+	sb.WriteString(busybox.InColors(busybox.BrightWhite, r.FullName) + "\n\n")
+	if r.Description != "" {
+		sb.WriteString(r.Description + "\n\n")
+	}
+	if r.Language != "" {
+		fmt.Fprintf(&sb, "Language: %s\n", r.Language)
+	}
+	fmt.Fprintf(&sb, "Stars:    %d\n", r.Stars)
+	fmt.Fprintf(&sb, "Clone:    %s\n", r.URL)
+	return sb.String()
+}
 
-	choice, err := busybox.RunTerm(t.Sessions, func(s TmuxSession) string { return s.Name })
-	if err != nil {
-		return err
+// Tn lets the user pick a session to switch to, the ones switched to most
+// and most recently first. Ctrl-X kills the current session and Ctrl-R
+// renames it, the picker comes back after both.
+func (t *Tmux) Tn() error {
+	store := openFrecency(sessionsStore)
+	sessions := func() []TmuxSession {
+		// t.Current points into t.Sessions, sort a copy
+		sorted := slices.Clone(t.Sessions)
+		frecency.SortFunc(store, sorted, func(s TmuxSession) string { return s.Name })
+		return sorted
 	}
 
-	err = t.SwitchSession(choice.Name)
-	if err != nil {
-		return err
+	formatter := busybox.ItemFormatter[TmuxSession]{
+		ToString: func(s TmuxSession) string { return s.Name },
+		Actions: map[busybox.Key]busybox.Action[TmuxSession]{
+			busybox.CtrlX: {
+				Name: "kill",
+				Run: func(s TmuxSession) ([]TmuxSession, error) {
+					if err := t.KillSession(s.Name); err != nil {
+						return nil, err
+					}
+					return sessions(), nil
+				},
+			},
+			busybox.CtrlR: {Name: "rename"},
+		},
+		History: "tn",
 	}
 
-	return nil
+	for {
+		choice, action, err := busybox.RunTermAction(sessions(), formatter)
+		if err != nil {
+			return err
+		}
+
+		if action != "rename" {
+			remember(store, choice.Name)
+			return t.SwitchSession(choice.Name)
+		}
+
+		newName, err := busybox.ReadLine(fmt.Sprintf("Rename %s to: ", choice.Name), choice.Name)
+		if errors.Is(err, busybox.ErrCancelled) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if newName != "" && newName != choice.Name {
+			if err := t.RenameSession(choice.Name, newName); err != nil {
+				return err
+			}
+			// The session keeps its rank under its new name
+			store.Rename(choice.Name, newName)
+			saveFrecency(store)
+		}
+	}
 }
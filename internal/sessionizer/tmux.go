@@ -2,6 +2,7 @@ package sessionizer
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -15,6 +16,10 @@ import (
 
 var ErrNotInTmux = errors.New("not in a tmux session")
 
+// ErrNoServer is returned by [Tmux.Run] when no tmux server is running, as
+// after the last session was killed
+var ErrNoServer = errors.New("no tmux server running")
+
 type Tmux struct {
 	Current      *TmuxSession
 	CurrentIndex int
@@ -119,6 +124,11 @@ func (t *Tmux) UpdatePosition(sessionName string) {
 }
 
 func (t *Tmux) Run(args ...string) (string, error) {
+	return t.RunContext(context.Background(), args...)
+}
+
+// RunContext is [Tmux.Run] killing tmux when ctx is done
+func (t *Tmux) RunContext(ctx context.Context, args ...string) (string, error) {
 	if len(args) == 0 {
 		return "", fmt.Errorf("no command specified")
 	}
@@ -127,12 +137,18 @@ func (t *Tmux) Run(args ...string) (string, error) {
 		return "", err
 	}
 
-	cmd := t.buildCommand(args)
+	cmd := t.buildCommand(ctx, args)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if isNoServer(stderr.String()) {
+			return "", fmt.Errorf("%w: %s", ErrNoServer, strings.TrimSpace(stderr.String()))
+		}
 		fmt.Fprint(os.Stderr, stderr.String())
 		return "", fmt.Errorf("%w: %s", err, stderr.String())
 	}
@@ -140,6 +156,14 @@ func (t *Tmux) Run(args ...string) (string, error) {
 	return stdout.String(), nil
 }
 
+// isNoServer reports whether stderr of tmux says there is no server to talk
+// to. The server may also exit while answering, after its last session.
+func isNoServer(stderr string) bool {
+	return strings.HasPrefix(stderr, "no server running") ||
+		strings.HasPrefix(stderr, "error connecting to") ||
+		strings.HasPrefix(stderr, "server exited unexpectedly")
+}
+
 func (t *Tmux) requiresTerminal(command string) error {
 	insideTmux := os.Getenv("TMUX") != ""
 
@@ -152,9 +176,9 @@ func (t *Tmux) requiresTerminal(command string) error {
 	return nil
 }
 
-func (t *Tmux) buildCommand(args []string) *exec.Cmd {
+func (t *Tmux) buildCommand(ctx context.Context, args []string) *exec.Cmd {
 	if os.Getenv("TMUX") != "" {
-		return exec.Command("tmux", args...)
+		return exec.CommandContext(ctx, "tmux", args...)
 	}
 
 	// Quote args with special characters
@@ -167,11 +191,14 @@ func (t *Tmux) buildCommand(args []string) *exec.Cmd {
 		}
 	}
 
-	return exec.Command("bash", "-c", "tmux "+strings.Join(quoted, " "))
+	return exec.CommandContext(ctx, "bash", "-c", "tmux "+strings.Join(quoted, " "))
 }
 
 func (t *Tmux) HasSession(sessionName string) (bool, error) {
 	stdout, err := t.Run("list-sessions", "-F", "#{session_name}")
+	if errors.Is(err, ErrNoServer) {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("failed to list sessions: %w", err)
 	}
@@ -237,8 +264,13 @@ func (t *Tmux) LoadSessions() error {
 	return nil
 }
 
+// GetSessions lists the sessions of the tmux server, none when it isn't
+// running
 func (t *Tmux) GetSessions() ([]TmuxSession, error) {
 	stdout, err := t.Run("list-sessions", "-F", "#{session_created}|#{session_name}|#{session_group}|#{?session_attached,1,0}")
+	if errors.Is(err, ErrNoServer) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -313,6 +345,16 @@ func (t *Tmux) SwitchWindow(sessionName string, windowIndex int) error {
 }
 
 func (t *Tmux) CreateSession(name string, absPath string) error {
+	sessionName, err := t.NewSession(name, absPath)
+	if err != nil {
+		return err
+	}
+	return t.SwitchSession(sessionName)
+}
+
+// NewSession creates a detached session in absPath unless it already exists
+// and returns the session name actually used.
+func (t *Tmux) NewSession(name string, absPath string) (string, error) {
 	sessionName := name
 	if strings.HasPrefix(name, ".") {
 		sessionName = name[1:]
@@ -320,21 +362,37 @@ func (t *Tmux) CreateSession(name string, absPath string) error {
 
 	exists, err := t.HasSession(sessionName)
 	if err != nil {
-		return errors.New("failed to check if session exists")
+		return "", errors.New("failed to check if session exists")
 	}
 
 	if !exists {
 		_, err = t.Run("new-session", "-d", "-s", sessionName, "-c", absPath)
 		if err != nil {
-			return fmt.Errorf("failed to create the session name:%v, path:%v: %w", sessionName, absPath, err)
+			return "", fmt.Errorf("failed to create the session name:%v, path:%v: %w", sessionName, absPath, err)
 		}
 
 		if err := t.LoadSessions(); err != nil {
-			return fmt.Errorf("failed to reload sessions: %w", err)
+			return "", fmt.Errorf("failed to reload sessions: %w", err)
 		}
 
 		t.UpdatePosition(sessionName)
 	}
 
-	return t.SwitchSession(sessionName)
+	return sessionName, nil
+}
+
+// KillSession kills the session and drops it from t.Sessions
+func (t *Tmux) KillSession(sessionName string) error {
+	if _, err := t.Run("kill-session", "-t", sessionName); err != nil && !errors.Is(err, ErrNoServer) {
+		return fmt.Errorf("failed to kill the session %v: %w", sessionName, err)
+	}
+	return t.LoadSessions()
+}
+
+// RenameSession renames the session and reloads t.Sessions
+func (t *Tmux) RenameSession(sessionName string, newName string) error {
+	if _, err := t.Run("rename-session", "-t", sessionName, newName); err != nil {
+		return fmt.Errorf("failed to rename the session %v: %w", sessionName, err)
+	}
+	return t.LoadSessions()
 }
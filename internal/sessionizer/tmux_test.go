@@ -0,0 +1,53 @@
+package sessionizer
+
+import (
+	"os/exec"
+	"testing"
+)
+
+// newTestTmux returns a Tmux talking to a server of its own, or skips the
+// test when tmux isn't installed
+func newTestTmux(t *testing.T) *Tmux {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed")
+	}
+	t.Setenv("TMUX", "")
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+
+	tmux, err := NewTmux()
+	if err != nil {
+		t.Fatalf("Expected no sessions without a server, got %v", err)
+	}
+	t.Cleanup(func() { tmux.Run("kill-server") })
+	return tmux
+}
+
+func TestKillLastSession(t *testing.T) {
+	tmux := newTestTmux(t)
+	for _, name := range []string{"dev", "ops"} {
+		if _, err := tmux.Run("new-session", "-d", "-s", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tmux.RenameSession("ops", "notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tmux.KillSession("dev"); err != nil {
+		t.Fatal(err)
+	}
+	if len(tmux.Sessions) != 1 || tmux.Sessions[0].Name != "notes" {
+		t.Fatalf("Expected the notes session to be left, got %+v", tmux.Sessions)
+	}
+
+	// The server exits with its last session
+	if err := tmux.KillSession("notes"); err != nil {
+		t.Errorf("Expected killing the last session to work, got %v", err)
+	}
+	if len(tmux.Sessions) != 0 {
+		t.Errorf("Expected no sessions, got %+v", tmux.Sessions)
+	}
+	if ok, err := tmux.HasSession("notes"); ok || err != nil {
+		t.Errorf("Expected no session without a server, got %v, %v", ok, err)
+	}
+}
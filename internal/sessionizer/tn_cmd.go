@@ -1,6 +1,7 @@
 package sessionizer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -19,7 +20,8 @@ var TnCmd = &cobra.Command{
 Available commands:
   create   Create a new tmux session in the current or specified directory
   window   Switch between windows across sessions
-  (run without arguments to switch sessions)
+  kill     Kill one or more sessions (Tab marks, Ctrl-A marks all)
+  (run without arguments to switch sessions, Ctrl-X kills and Ctrl-R renames)
 `,
 
 	SilenceUsage: true,
@@ -73,7 +75,7 @@ var createCmd = &cobra.Command{
 var windowsCmd = &cobra.Command{
 	Use:          "windows",
 	Aliases:      []string{"w"},
-	Short:        "Switch between windows across all sessions",
+	Short:        "Switch between windows across all sessions, or to a session from its header",
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		tmux, err := NewTmux()
@@ -98,16 +100,75 @@ var windowsCmd = &cobra.Command{
 				return w.SessionName
 			},
 			Separator: busybox.InColors(busybox.BrightCyan, "● {{GROUP}}"),
+			// A header stands for its session, shown at its current window
+			GroupItem: func(session string) TmuxWindow {
+				return TmuxWindow{SessionName: session, Index: sessionWindow}
+			},
+			Preview: func(ctx context.Context, w TmuxWindow) string {
+				target := w.SessionName
+				if w.Index != sessionWindow {
+					target = fmt.Sprintf("%s:%d", w.SessionName, w.Index)
+				}
+				out, err := tmux.RunContext(ctx, "capture-pane", "-p", "-e", "-t", target)
+				if err != nil {
+					return err.Error()
+				}
+				return out
+			},
+			History: "tn-windows",
+			// Windows are told apart by their session, headers aren't remembered
+			HistoryKey: func(w TmuxWindow) string {
+				if w.Index == sessionWindow {
+					return ""
+				}
+				return w.SessionName + ":" + w.Name
+			},
 		})
 		if err != nil {
 			return err
 		}
 
+		if choice.Index == sessionWindow {
+			return tmux.SwitchSession(choice.SessionName)
+		}
 		return tmux.SwitchWindow(choice.SessionName, choice.Index)
 	},
 }
 
+// sessionWindow is the index of the window picked with a session header,
+// the session is switched to as it is
+const sessionWindow = -1
+
+var killCmd = &cobra.Command{
+	Use:          "kill",
+	Aliases:      []string{"k"},
+	Short:        "Pick sessions to kill, Tab marks several",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tmux, err := NewTmux()
+		if err != nil {
+			return err
+		}
+
+		choices, err := busybox.RunTermMulti(tmux.Sessions, busybox.ItemFormatter[TmuxSession]{
+			ToString: func(s TmuxSession) string { return s.Name },
+		})
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, s := range choices {
+			if err := tmux.KillSession(s.Name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	},
+}
+
 func init() {
 	TnCmd.AddCommand(createCmd)
 	TnCmd.AddCommand(windowsCmd)
+	TnCmd.AddCommand(killCmd)
 }
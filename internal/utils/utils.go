@@ -2,14 +2,16 @@ package utils
 
 import (
 	"fmt"
+	"os"
 	"time"
 )
 
+// WaitingScreen draws a spinner with desc on stderr until done receives
 func WaitingScreen(done chan bool, desc string) {
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				fmt.Println("Recovered from error:", r)
+				fmt.Fprintln(os.Stderr, "Recovered from error:", r)
 				done <- true
 			}
 		}()
@@ -20,7 +22,7 @@ func WaitingScreen(done chan bool, desc string) {
 				return
 			default:
 				for _, r := range `-\|/` {
-					fmt.Printf("\r%c %s", r, desc)
+					fmt.Fprintf(os.Stderr, "\r%c %s", r, desc)
 					time.Sleep(100 * time.Millisecond)
 				}
 			}
@@ -0,0 +1,141 @@
+package busybox
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// ansiSequence matches CSI escape sequences such as colors and cursor moves
+var (
+	ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+	ansiPrefix   = regexp.MustCompile(`^\x1b\[[0-9;?]*[ -/]*[@-~]`)
+)
+
+// StripANSI returns the text of s as it appears on screen, without escape sequences
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiSequence.ReplaceAllString(s, "")
+}
+
+// visibleWidth returns how many terminal columns s takes, wide runes like
+// CJK and emoji count twice and escape sequences not at all
+func visibleWidth(s string) int {
+	return runewidth.StringWidth(StripANSI(s))
+}
+
+// highlightMatches colors the visible runes of s at positions (as returned by
+// [Pattern.Match] on [StripANSI] of s) with c. Escape sequences already in s
+// are kept and the colors they set are restored after every highlighted run.
+func highlightMatches(s string, positions []int, c Color) string {
+	if len(positions) == 0 {
+		return s
+	}
+
+	var buf strings.Builder
+	active := ""   // SGR sequences in effect since the last reset
+	inRun := false // Inside a highlighted run of runes
+	next := 0      // Index into positions
+	visible := 0   // Index of the current visible rune
+
+	endRun := func() {
+		if inRun {
+			buf.WriteString(string(Reset) + active)
+			inRun = false
+		}
+	}
+
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			if seq := ansiPrefix.FindString(s[i:]); seq != "" {
+				endRun()
+				buf.WriteString(seq)
+				if strings.HasSuffix(seq, "m") {
+					if seq == "\x1b[0m" || seq == "\x1b[m" {
+						active = ""
+					} else {
+						active += seq
+					}
+				}
+				i += len(seq)
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+
+		for next < len(positions) && positions[next] < visible {
+			next++
+		}
+		if next < len(positions) && positions[next] == visible {
+			if !inRun {
+				buf.WriteString(string(c))
+				inRun = true
+			}
+		} else {
+			endRun()
+		}
+
+		buf.WriteString(s[i : i+size])
+		visible++
+		i += size
+	}
+	endRun()
+
+	return buf.String()
+}
+
+// truncateVisible cuts s to width terminal columns, keeping its escape
+// sequences intact and resetting colors if anything was cut. A wide rune
+// that would only half fit is cut too.
+func truncateVisible(s string, width int) string {
+	if visibleWidth(s) <= width {
+		return s
+	}
+
+	var buf strings.Builder
+	visible := 0 // Columns taken so far
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			if seq := ansiPrefix.FindString(s[i:]); seq != "" {
+				buf.WriteString(seq)
+				i += len(seq)
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runewidth.RuneWidth(r)
+		if visible+w > width {
+			buf.WriteString(string(Reset))
+			return buf.String()
+		}
+		visible += w
+		buf.WriteString(s[i : i+size])
+		i += size
+	}
+	return buf.String()
+}
+
+// styledLines splits s into lines that each start with the colors in effect
+// where they begin, so that any of them can be drawn on its own
+func styledLines(s string) []string {
+	lines := strings.Split(s, "\n")
+	active := "" // SGR sequences in effect since the last reset
+	for i, line := range lines {
+		lines[i] = active + line
+		for _, seq := range ansiSequence.FindAllString(line, -1) {
+			switch {
+			case !strings.HasSuffix(seq, "m"):
+			case seq == "\x1b[0m" || seq == "\x1b[m":
+				active = ""
+			default:
+				active += seq
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,111 @@
+package busybox
+
+import (
+	"fmt"
+	"testing"
+)
+
+// matchColor marks the matched runes in the default theme
+const matchColor Color = "\033[1;32m"
+
+// [StripANSI] removes the colors added with [InColors]
+func ExampleStripANSI() {
+	window := InColors(BrightWhite, "editor") + "  " + InColors(BrightBlack, "[1]")
+	fmt.Printf("%q\n", StripANSI(window))
+	// Output:
+	// "editor  [1]"
+}
+
+func TestHighlightMatches(t *testing.T) {
+	const hl = matchColor
+	testCases := []struct {
+		name      string
+		text      string
+		positions []int
+		want      string
+	}{
+		{
+			name:      "plain text",
+			text:      "gosh",
+			positions: []int{0, 2, 3},
+			want:      string(hl) + "g" + string(Reset) + "o" + string(hl) + "sh" + string(Reset),
+		},
+		{
+			name:      "restores the item color",
+			text:      string(Cyan) + "ab" + string(Reset),
+			positions: []int{0},
+			want:      string(Cyan) + string(hl) + "a" + string(Reset) + string(Cyan) + "b" + string(Reset),
+		},
+		{
+			name:      "positions count visible runes only",
+			text:      string(Red) + "ż" + string(Reset) + "ółw",
+			positions: []int{2},
+			want:      string(Red) + "ż" + string(Reset) + "ó" + string(hl) + "ł" + string(Reset) + "w",
+		},
+		{
+			name:      "no positions",
+			text:      string(Red) + "x" + string(Reset),
+			positions: nil,
+			want:      string(Red) + "x" + string(Reset),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := highlightMatches(tc.text, tc.positions, hl)
+			if got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+			if StripANSI(got) != StripANSI(tc.text) {
+				t.Errorf("Highlighting changed the visible text: %q", StripANSI(got))
+			}
+		})
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	testCases := []struct {
+		text string
+		want int
+	}{
+		{text: "gosh", want: 4},
+		{text: "zażółć", want: 6},
+		{text: "日本語", want: 6},
+		{text: InColors(Cyan, "🚀 gosh"), want: 7},
+	}
+
+	for _, tc := range testCases {
+		if got := visibleWidth(tc.text); got != tc.want {
+			t.Errorf("Expected %q to be %d columns wide, got %d", tc.text, tc.want, got)
+		}
+	}
+}
+
+func TestTruncateVisible(t *testing.T) {
+	testCases := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{text: "gosh", width: 10, want: "gosh"},
+		{text: "gosh", width: 2, want: "go" + string(Reset)},
+		{text: "zażółć", width: 4, want: "zażó" + string(Reset)},
+		{
+			text:  string(Cyan) + "abc" + string(Reset),
+			width: 2,
+			want:  string(Cyan) + "ab" + string(Reset),
+		},
+		// Wide runes take two columns, one that would only half fit is cut
+		{text: "日本語", width: 6, want: "日本語"},
+		{text: "日本語", width: 4, want: "日本" + string(Reset)},
+		{text: "日本語", width: 3, want: "日" + string(Reset)},
+		{text: "🚀 gosh", width: 4, want: "🚀 g" + string(Reset)},
+	}
+
+	for _, tc := range testCases {
+		got := truncateVisible(tc.text, tc.width)
+		if got != tc.want {
+			t.Errorf("Expected %q, got %q", tc.want, got)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package busybox
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,6 +24,7 @@ import (
 	_ "github.com/alecthomas/chroma/lexers/t" // for TOML
 	_ "github.com/alecthomas/chroma/lexers/y" // for YAML
 	"github.com/alecthomas/chroma/styles"
+	"github.com/mattn/go-runewidth"
 	"golang.org/x/term"
 )
 
@@ -48,27 +53,66 @@ func findLanguage(fileName string) string {
 	return "unknown"
 }
 
+// wheelLines is how far the pager scrolls for a turn of the mouse wheel
+const wheelLines = 3
+
 type HighlightedPager struct {
-	term     Term
-	filename string
-	content  io.Reader
+	term      Term
+	out       io.Writer
+	filename  string
+	content   io.Reader
+	style     string
+	pipeStyle string
 }
 
 func NewHighlightedPager(filename string, content io.Reader) *HighlightedPager {
 	return &HighlightedPager{
+		out:      os.Stdout,
 		filename: filename,
 		content:  content,
 	}
 }
 
+// SetStyle sets the chroma style used inside the pager, by default the one
+// of the theme in [Settings]
+func (hp *HighlightedPager) SetStyle(style string) *HighlightedPager {
+	if len(style) > 0 {
+		hp.style = style
+	}
+	return hp
+}
+
+// SetPipeStyle sets the chroma style used when stdout is not a terminal, by
+// default the one of the theme in [Settings]
+func (hp *HighlightedPager) SetPipeStyle(style string) *HighlightedPager {
+	if len(style) > 0 {
+		hp.pipeStyle = style
+	}
+	return hp
+}
+
+// SetTerm sets the terminal the pager runs on, by default [NewTerm] when
+// the output is a terminal. The pager leaves closing it to the caller.
+func (hp *HighlightedPager) SetTerm(t Term) *HighlightedPager {
+	hp.term = t
+	return hp
+}
+
+// SetOutput sets where the content is printed without paging when there
+// is no terminal, by default stdout
+func (hp *HighlightedPager) SetOutput(w io.Writer) *HighlightedPager {
+	hp.out = w
+	return hp
+}
+
 func (hp *HighlightedPager) Run() error {
-	// If stdout is not a terminal, just highlight and print
-	if !term.IsTerminal(int(os.Stdout.Fd())) {
+	// If the output is not a terminal, just highlight and print
+	if hp.term == nil && !isTerminal(hp.out) {
 		content, err := io.ReadAll(hp.content)
 		if err != nil {
 			return fmt.Errorf("reading content: %w", err)
 		}
-		return highlightCode(findLanguage(hp.filename), string(content))
+		return highlightCode(hp.out, findLanguage(hp.filename), string(content), hp.pipeStyle)
 	}
 
 	lines, err := hp.getHighlightedLines()
@@ -76,8 +120,22 @@ func (hp *HighlightedPager) Run() error {
 		return fmt.Errorf("highlighting content: %w", err)
 	}
 
-	hp.term = NewTerm()
-	defer hp.term.Close()
+	if hp.term == nil {
+		t, err := NewTerm()
+		if errors.Is(err, ErrNoTerminal) {
+			// Stdout is a terminal the process can't control, print instead
+			_, err = io.WriteString(hp.out, strings.Join(lines, "\n")+"\n")
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		hp.term = t
+		defer func() {
+			hp.term.Close()
+			hp.term = nil
+		}()
+	}
 
 	if err := hp.term.GetSize(); err != nil {
 		return fmt.Errorf("getting terminal size: %w", err)
@@ -86,121 +144,146 @@ func (hp *HighlightedPager) Run() error {
 	hp.term.EnterAltBuffer()
 	defer hp.term.ExitAltBuffer()
 
-	hp.displayLoop(lines)
-	return nil
+	return hp.displayLoop(lines)
 }
 
-func (hp *HighlightedPager) getHighlightedLines() ([]string, error) {
-	var buf bytes.Buffer
-
-	lang := findLanguage(hp.filename)
-	lexer := lexers.Get(lang)
-	if lexer == nil {
-		lexer = lexers.Fallback
-	}
+// isTerminal reports whether w writes to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
 
+func (hp *HighlightedPager) getHighlightedLines() ([]string, error) {
 	content, err := io.ReadAll(hp.content)
 	if err != nil {
 		return nil, fmt.Errorf("reading content: %w", err)
 	}
 
-	formatter := formatters.Get("terminal")
-	style := styles.Vim
-
-	iterator, err := lexer.Tokenise(nil, string(content))
+	highlighted, err := Highlight(findLanguage(hp.filename), string(content), hp.style)
 	if err != nil {
-		return nil, fmt.Errorf("tokenizing content: %w", err)
+		return nil, err
 	}
 
-	if err := formatter.Format(&buf, style, iterator); err != nil {
-		return nil, fmt.Errorf("formatting content: %w", err)
-	}
+	// The terminal expands tabs to where the cursor is, lines are drawn apart
+	highlighted = strings.ReplaceAll(highlighted, "\t", "    ")
+	lines := styledLines(strings.TrimSuffix(highlighted, "\n"))
+	return lines, nil
+}
 
-	lines := strings.Split(buf.String(), "\n")
-	if len(lines) > 0 && lines[len(lines)-1] == "" {
-		lines = lines[:len(lines)-1]
+// Highlight returns content colored for the terminal as the language lang
+// using the chroma style styleName, or the one of the theme when it is empty
+func Highlight(lang string, content string, styleName string) (string, error) {
+	var buf bytes.Buffer
+	if err := highlightCode(&buf, lang, content, styleName); err != nil {
+		return "", err
 	}
-
-	return lines, nil
+	return buf.String(), nil
 }
 
-func (hp *HighlightedPager) displayLoop(lines []string) {
+// displayLoop pages through lines until the pager is quit or the terminal
+// is interrupted
+func (hp *HighlightedPager) displayLoop(lines []string) error {
 	offset := 0
 	pageSize := hp.term.Height() - 1 // Save space for status line
 
-	hp.renderScreen(lines, offset, pageSize)
+	scr := &screen{moveTo: func(row, col int) string { return MoveCursorTo(row+1, col+1) }}
+	fmt.Fprint(hp.term, scr.draw(hp.render(lines, offset, pageSize)))
 
+	events, ctx := hp.term.Events(), hp.term.Context()
 	for {
-		if !hp.handleNavigation(&offset, lines, pageSize) {
-			return
+		select {
+		case ev, ok := <-events:
+			// q and Escape quit, Ctrl-C leaves like it leaves the picker
+			if ev.Key == CtrlC {
+				return ErrCancelled
+			}
+			if !ok || !hp.handleNavigation(ev, &offset, lines, pageSize) {
+				return nil
+			}
+			if ev.Key == Resize {
+				scr.reset()
+			}
+		case <-ctx.Done():
+			return context.Cause(ctx)
 		}
-		hp.renderScreen(lines, offset, pageSize)
+		// The size changes on Resize, keep the last page full
+		pageSize = hp.term.Height() - 1
+		offset = max(0, min(offset, len(lines)-pageSize))
+		fmt.Fprint(hp.term, scr.draw(hp.render(lines, offset, pageSize)))
 	}
 }
 
-func (hp *HighlightedPager) renderScreen(lines []string, offset, pageSize int) {
-	var buf bytes.Buffer
-
-	buf.WriteString(MoveCursorHome)
-	buf.WriteString(ClearToEOS)
-
+// render returns the page of lines from offset with the status line under it
+func (hp *HighlightedPager) render(lines []string, offset, pageSize int) *frame {
+	f := newFrame(hp.term.Width(), hp.term.Height())
 	for i := 0; i < pageSize && offset+i < len(lines); i++ {
-		buf.WriteString(lines[offset+i])
-		buf.WriteString(ClearToEOL)
-		buf.WriteString(CRLF)
+		f.put(i, 0, lines[offset+i])
 	}
-
-	hp.buildStatusLine(&buf, offset, len(lines))
-
-	fmt.Print(buf.String())
+	f.put(hp.term.Height()-1, 0, hp.statusLine(offset, len(lines)))
+	return f
 }
 
-func (hp *HighlightedPager) buildStatusLine(buf *bytes.Buffer, offset, totalLines int) {
-	buf.WriteString(MoveCursorTo(hp.term.Height(), 1))
-
+func (hp *HighlightedPager) statusLine(offset, totalLines int) string {
+	var buf strings.Builder
 	buf.WriteString(InverseVideo)
 
 	statusText := fmt.Sprintf(" File: %s | Line: %d/%d | Press q/Esc to quit ",
 		hp.filename, offset+1, totalLines)
 
-	// Truncate status if too long
-	termWidth := hp.term.Width()
-	if len(statusText) > termWidth {
-		statusText = statusText[:termWidth-3] + "..."
+	// Truncate status if too long, the ellipsis only when there is room for it
+	termWidth := max(0, hp.term.Width())
+	tail := "..."
+	if termWidth < len(tail) {
+		tail = ""
 	}
+	statusText = runewidth.Truncate(statusText, termWidth, tail)
 
 	buf.WriteString(statusText)
 
 	// Pad to full width
-	padding := termWidth - len(statusText)
-	if padding > 0 {
+	if padding := termWidth - visibleWidth(statusText); padding > 0 {
 		buf.WriteString(strings.Repeat(" ", padding))
 	}
 
 	buf.WriteString(ResetFormatting)
+	return buf.String()
 }
 
-func highlightCode(lang string, content string) error {
+// chromaFormatters writes highlighted code in the colors of each [ColorMode]
+var chromaFormatters = map[ColorMode]string{
+	NoColors:    "noop",
+	BasicColors: "terminal16",
+	Colors256:   "terminal256",
+	TrueColor:   "terminal16m",
+}
+
+func highlightCode(w io.Writer, lang string, content string, styleName string) error {
 	lexer := lexers.Get(lang)
 	if lexer == nil {
 		lexer = lexers.Fallback
 	}
 
-	formatter := formatters.Get("terminal")
-	style := styles.Monokai
+	formatter := formatters.Get(chromaFormatters[settings.Colors])
+	style := styles.Get(cmp.Or(styleName, settings.Theme.Style))
 
 	iterator, err := lexer.Tokenise(nil, content)
 	if err != nil {
-		return err
+		return fmt.Errorf("tokenizing content: %w", err)
 	}
 
-	return formatter.Format(os.Stdout, style, iterator)
+	if err := formatter.Format(w, style, iterator); err != nil {
+		return fmt.Errorf("formatting content: %w", err)
+	}
+	return nil
 }
 
-func (hp *HighlightedPager) handleNavigation(offset *int, lines []string, pageSize int) bool {
-	key, ch := hp.term.Read()
-	switch key {
-	case CtrlC, Escape:
+func (hp *HighlightedPager) handleNavigation(ev Event, offset *int, lines []string, pageSize int) bool {
+	switch ch := ev.Rune; ev.Key {
+	case Resize:
+		if err := hp.term.GetSize(); err != nil {
+			slog.Debug("Failed to get the terminal size", "error", err)
+		}
+	case Escape:
 		return false
 	case Other:
 		switch ch {
@@ -248,6 +331,18 @@ func (hp *HighlightedPager) handleNavigation(offset *int, lines []string, pageSi
 		if *offset < maxOffset {
 			*offset++
 		}
+	case PageUp:
+		*offset = max(0, *offset-pageSize)
+	case PageDown:
+		*offset = max(0, min(*offset+pageSize, len(lines)-pageSize))
+	case Home:
+		*offset = 0
+	case End:
+		*offset = max(0, len(lines)-pageSize)
+	case WheelUp:
+		*offset = max(0, *offset-wheelLines)
+	case WheelDown:
+		*offset = max(0, min(*offset+wheelLines, len(lines)-pageSize))
 	}
 
 	return true
@@ -0,0 +1,127 @@
+package busybox
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Escape codes that make the terminal show what is drawn between them at
+// once. Terminals that don't support synchronized updates ignore them.
+const (
+	BeginSyncUpdate = "\033[?2026h"
+	EndSyncUpdate   = "\033[?2026l"
+)
+
+// frame is one picture of the picker or the pager, kept as styled lines so
+// that [screen] only rewrites the lines that changed since the last one
+type frame struct {
+	width  int
+	rows   [][]segment
+	cursor struct {
+		row, col int
+		shown    bool
+	}
+}
+
+// segment is text drawn from a column of a row
+type segment struct {
+	col  int
+	text string
+}
+
+func newFrame(width, height int) *frame {
+	return &frame{width: width, rows: make([][]segment, max(0, height))}
+}
+
+// put draws text, which doesn't break lines, from column col of row. What
+// falls outside the frame is cut.
+func (f *frame) put(row, col int, text string) {
+	if row < 0 || row >= len(f.rows) || col < 0 || col >= f.width || text == "" {
+		return
+	}
+	f.rows[row] = append(f.rows[row], segment{col: col, text: text})
+}
+
+// showCursor puts the terminal cursor at row and col once the frame is drawn
+func (f *frame) showCursor(row, col int) {
+	f.cursor.row, f.cursor.col, f.cursor.shown = row, col, true
+}
+
+// line returns row as it is written to the terminal, the segments left to
+// right with spaces between them. A segment starting inside the one before
+// it is drawn right after it.
+func (f *frame) line(row int) string {
+	segments := slices.SortedStableFunc(slices.Values(f.rows[row]), func(a, b segment) int {
+		return cmp.Compare(a.col, b.col)
+	})
+
+	var b strings.Builder
+	col := 0
+	for _, s := range segments {
+		if s.col > col {
+			b.WriteString(strings.Repeat(" ", s.col-col))
+			col = s.col
+		}
+		if col >= f.width {
+			break
+		}
+		text := truncateVisible(s.text, f.width-col)
+		b.WriteString(text)
+		if strings.Contains(text, "\x1b") && !strings.HasSuffix(text, string(Reset)) {
+			// Colors don't leak into the next segment or line
+			b.WriteString(string(Reset))
+		}
+		col += visibleWidth(text)
+	}
+	return b.String()
+}
+
+// screen draws frames on a terminal. Only the lines that differ from the
+// last frame are written, inside a synchronized update.
+type screen struct {
+	moveTo func(row, col int) string // Moves the cursor to a row and column of the frame
+	last   []string                  // Lines of the last frame, nil to draw everything
+}
+
+// draw returns what to write to the terminal to show f
+func (s *screen) draw(f *frame) string {
+	var buf strings.Builder
+	buf.WriteString(BeginSyncUpdate + HideCursor)
+
+	repaint := s.last == nil
+	if repaint {
+		buf.WriteString(s.moveTo(0, 0) + ClearToEOS)
+	}
+
+	lines := make([]string, len(f.rows))
+	for row := range max(len(lines), len(s.last)) {
+		if row >= len(lines) {
+			// The frame got shorter
+			if s.last[row] != "" {
+				buf.WriteString(s.moveTo(row, 0) + ClearToEOL)
+			}
+			continue
+		}
+
+		lines[row] = f.line(row)
+		unchanged := row < len(s.last) && lines[row] == s.last[row]
+		if repaint && lines[row] == "" || !repaint && unchanged {
+			continue
+		}
+		buf.WriteString(s.moveTo(row, 0) + lines[row] + ClearToEOL)
+	}
+
+	if f.cursor.shown {
+		buf.WriteString(s.moveTo(f.cursor.row, f.cursor.col) + ShowCursor)
+	}
+	buf.WriteString(EndSyncUpdate)
+	s.last = lines
+	return buf.String()
+}
+
+// reset makes the next draw clear the screen and draw every line, the
+// terminal may have moved or wrapped the old ones after a resize
+func (s *screen) reset() {
+	s.last = nil
+}
@@ -0,0 +1,78 @@
+package busybox
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFrameLine(t *testing.T) {
+	f := newFrame(10, 2)
+	f.put(0, 4, "gosh")
+	f.put(0, 0, "> ")
+	f.put(0, 9, "tn and more")
+	f.put(1, 0, InColors(Cyan, "a long line"))
+	f.put(2, 0, "outside")
+	f.put(0, 10, "outside")
+
+	if got := f.line(0); got != ">   gosh t"+string(Reset) {
+		t.Errorf("Expected the segments in order of columns, got %q", got)
+	}
+	if got := f.line(1); got != InColors(Cyan, "a long lin") {
+		t.Errorf("Expected a truncated line with its colors reset, got %q", got)
+	}
+
+	// The column after wide runes counts their cells
+	f = newFrame(10, 1)
+	f.put(0, 0, "日本語")
+	f.put(0, 8, "│x")
+	if got := f.line(0); got != "日本語  │x" {
+		t.Errorf("Expected wide runes to take two columns each, got %q", got)
+	}
+}
+
+func TestScreenDraw(t *testing.T) {
+	scr := &screen{moveTo: func(row, col int) string { return fmt.Sprintf("<%d,%d>", row, col) }}
+	frameOf := func(lines ...string) *frame {
+		f := newFrame(20, len(lines))
+		for i, line := range lines {
+			f.put(i, 0, line)
+		}
+		return f
+	}
+
+	first := scr.draw(frameOf("> tn", "fd", "pick"))
+	if !strings.HasPrefix(first, BeginSyncUpdate) || !strings.HasSuffix(first, EndSyncUpdate) {
+		t.Errorf("Expected a synchronized update, got %q", first)
+	}
+	if !strings.Contains(first, "<0,0>"+ClearToEOS) || !strings.Contains(first, "<2,0>pick") {
+		t.Errorf("Expected the first frame to be drawn in full, got %q", first)
+	}
+
+	second := scr.draw(frameOf("> tn", "s"))
+	if strings.Contains(second, "tn") || !strings.Contains(second, "<1,0>s"+ClearToEOL) {
+		t.Errorf("Expected only the changed line to be drawn, got %q", second)
+	}
+	if !strings.Contains(second, "<2,0>"+ClearToEOL) {
+		t.Errorf("Expected the row left by the longer frame to be cleared, got %q", second)
+	}
+
+	f := frameOf("> tn", "s")
+	f.showCursor(0, 4)
+	if got := scr.draw(f); got != BeginSyncUpdate+HideCursor+"<0,4>"+ShowCursor+EndSyncUpdate {
+		t.Errorf("Expected only the cursor to move, got %q", got)
+	}
+
+	scr.reset()
+	if got := scr.draw(frameOf("> tn", "s")); !strings.Contains(got, "<0,0>"+ClearToEOS+"<0,0>> tn") {
+		t.Errorf("Expected a reset to draw everything, got %q", got)
+	}
+}
+
+func TestStyledLines(t *testing.T) {
+	got := styledLines("\033[31mone\ntwo\033[0m\nthree")
+	want := []string{"\033[31mone", "\033[31mtwo\033[0m", "three"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -1,12 +1,14 @@
 package busybox
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"os"
-	"os/signal"
+	"iter"
+	"log/slog"
+	"slices"
 	"strings"
-	"syscall"
+	"time"
 )
 
 // ItemFormatter allows customizing display with optional grouping
@@ -14,8 +16,56 @@ type ItemFormatter[T any] struct {
 	ToString  func(T) string
 	GetGroup  func(T) string // Optional: return group name for grouping
 	Separator string         // Optional: custom separator between groups (default: blank line)
+
+	// Optional: the item a group stands for. With it the headers made from
+	// Separator can be selected, and choosing one returns GroupItem(group).
+	GroupItem func(group string) T
+
+	// Optional: text shown next to the list for the current item. It runs in
+	// the background, its context is cancelled once the selection moves on and
+	// the result is then dropped. Shift-Up/Down scroll the preview.
+	Preview         func(context.Context, T) string
+	PreviewPosition string // "right" or "bottom", by default right on wide terminals
+
+	Query string // Optional: the query the picker starts with
+
+	// Optional: extra keys of the picker and the actions they trigger
+	Actions map[Key]Action[T]
+
+	// Optional: the terminal the picker runs on, by default [NewTerm]. The
+	// picker leaves closing it to the caller.
+	Term Term
+
+	// Optional: name of the [History] the picker recalls queries from and
+	// records what was chosen in, usually the command
+	History string
+
+	// Optional: what the history remembers an item by, by default its text.
+	// Items it returns "" for aren't remembered.
+	HistoryKey func(T) string
+
+	// Optional: how the picker is drawn, by default the Layout in [Settings]
+	Layout *Layout
 }
 
+// Action is triggered by its key on the current item of the picker
+type Action[T any] struct {
+	Name string // Returned in [Result] when the action closes the picker
+
+	// Optional: runs the action with the picker open and returns the items
+	// to show from then on. Without it the picker closes and returns Name.
+	Run func(item T) ([]T, error)
+}
+
+// Result is what the picker was closed with
+type Result[T any] struct {
+	Items  []T    // The marked items, or the current one when none are
+	Action string // Name of the action that closed the picker, "" for Enter
+}
+
+// ErrCancelled is returned when the user leaves the picker without choosing
+var ErrCancelled = errors.New("cancelled")
+
 // RunTerm is the backward-compatible simple version
 func RunTerm[T any](items []T, toString func(T) string) (T, error) {
 	return RunTermGrouped(items, ItemFormatter[T]{
@@ -24,136 +74,607 @@ func RunTerm[T any](items []T, toString func(T) string) (T, error) {
 }
 
 func RunTermGrouped[T any](items []T, formatter ItemFormatter[T]) (T, error) {
+	if len(items) == 0 {
+		var zero T
+		return zero, errNoItems
+	}
+	return RunTermSeq(slices.Values(items), formatter)
+}
+
+// RunTermMulti lets the user pick several items. Tab toggles the mark on the
+// current item, Ctrl-A marks every visible item and Enter returns the marked
+// items in their original order, or the current one if nothing is marked.
+func RunTermMulti[T any](items []T, formatter ItemFormatter[T]) ([]T, error) {
+	if len(items) == 0 {
+		return nil, errNoItems
+	}
+	return RunTermSeqMulti(slices.Values(items), formatter)
+}
+
+// RunTermAction is [RunTermGrouped] that also returns the name of the
+// action in formatter.Actions that closed the picker, "" for Enter
+func RunTermAction[T any](items []T, formatter ItemFormatter[T]) (T, string, error) {
 	var zero T
 	if len(items) == 0 {
-		return zero, fmt.Errorf("no items available")
+		return zero, "", errNoItems
 	}
+	res, err := Pick(slices.Values(items), formatter, false)
+	if err != nil {
+		return zero, "", err
+	}
+	return res.Items[0], res.Action, nil
+}
 
-	lookup := make(map[string]T)
-	displayItems := make([]string, 0, len(items))
-	lastGroup := ""
+// RunTermSeq is [RunTermGrouped] for items that take a while to produce.
+// The picker shows up right away and items are added as items yields them,
+// with a spinner and a count until it is done. Items stops being iterated
+// once the picker is closed.
+func RunTermSeq[T any](items iter.Seq[T], formatter ItemFormatter[T]) (T, error) {
+	var zero T
+	res, err := Pick(items, formatter, false)
+	if err != nil {
+		return zero, err
+	}
+	return res.Items[0], nil
+}
 
-	for _, item := range items {
-		// Add group separator/header
-		if formatter.GetGroup != nil {
-			group := formatter.GetGroup(item)
-			if group != lastGroup {
-				if lastGroup != "" {
-					displayItems = append(displayItems, "")
-				}
-				// Add custom separator with group name replacement
-				if formatter.Separator != "" {
-					sep := strings.ReplaceAll(formatter.Separator, "{{GROUP}}", group)
-					displayItems = append(displayItems, sep) // Separator - NOT in lookup
-				}
-				lastGroup = group
+// RunTermSeqMulti is [RunTermMulti] for items that take a while to produce,
+// see [RunTermSeq]
+func RunTermSeqMulti[T any](items iter.Seq[T], formatter ItemFormatter[T]) ([]T, error) {
+	res, err := Pick(items, formatter, true)
+	return res.Items, err
+}
+
+// FromChan returns the items received on ch until it is closed, to pass
+// a channel to [RunTermSeq]
+func FromChan[T any](ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for item := range ch {
+			if !yield(item) {
+				return
 			}
 		}
-
-		s := formatter.ToString(item)
-		lookup[s] = item // Only actual items go in lookup
-		displayItems = append(displayItems, s)
 	}
+}
 
-	term := NewTerm()
-	defer term.Close()
+// Filter returns the items matching query, best matches first, as the picker
+// would list them. It runs without a terminal, for scripts and pipelines.
+func Filter[T any](items []T, formatter ItemFormatter[T], query string) []T {
+	sel := &selector[T]{formatter: formatter}
+	for _, item := range items {
+		sel.add(item)
+	}
+	sel.query.set(query)
 
-	if err := term.GetSize(); err != nil {
-		return zero, fmt.Errorf("getting terminal size: %w", err)
+	var matched []T
+	for _, r := range sel.filter() {
+		if r.kind == itemRow {
+			matched = append(matched, sel.items[r.item])
+		}
 	}
+	return matched
+}
+
+var errNoItems = errors.New("no items available")
 
-	termHeight := int(float64(term.Height()) * 0.8)
-	termHeight = max(5, termHeight)
+// rowKind is what a row of the picker shows
+type rowKind int
 
-	term.EnterAltBuffer()
-	defer term.ExitAltBuffer()
+const (
+	itemRow   rowKind = iota // An item, the kind that can always be selected
+	headerRow                // The separator starting a group
+	blankRow                 // The empty line between groups
+)
+
+// row is a line of the picker. Item rows refer to their item by index, so
+// items rendered the same stay apart and a header never passes for an item.
+type row struct {
+	kind      rowKind
+	text      string
+	group     string // Group the row belongs to, a blank row to the group after it
+	item      int    // Index into the items of the selector, for itemRow and selectable headers
+	positions []int  // Runes of text matched by the query, on filtered rows
+}
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(sigChan)
+// streamItems iterates items in the background. They are sent on the
+// returned channel, which is closed when items ends or stop is closed.
+func streamItems[T any](items iter.Seq[T], stop <-chan struct{}) <-chan T {
+	ch := make(chan T, 64)
 	go func() {
-		<-sigChan
-		term.ExitAltBuffer()
-		Quit(term)
+		defer close(ch)
+		for item := range items {
+			select {
+			case ch <- item:
+			case <-stop:
+				return
+			}
+		}
 	}()
+	return ch
+}
+
+const (
+	spinnerInterval = 100 * time.Millisecond
+	streamBatch     = 1024 // Items added at most between two redraws
+)
+
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// doubleClick is the longest time between the two clicks of a double click
+const doubleClick = 400 * time.Millisecond
+
+// Pick runs the picker over items as they are yielded, see [RunTermSeq],
+// and returns the chosen items with the action that closed it. With multi
+// several items can be marked, see [RunTermMulti].
+func Pick[T any](items iter.Seq[T], formatter ItemFormatter[T], multi bool) (Result[T], error) {
+	term := formatter.Term
+	if term == nil {
+		var err error
+		if term, err = NewTerm(); err != nil {
+			return Result[T]{}, err
+		}
+		defer term.Close()
+	}
+
+	if err := term.GetSize(); err != nil {
+		return Result[T]{}, fmt.Errorf("getting terminal size: %w", err)
+	}
+
+	layout := settings.Layout
+	if formatter.Layout != nil {
+		layout = *formatter.Layout
+	}
+	rows, err := layout.rows(term.Height())
+	if err != nil {
+		return Result[T]{}, err
+	}
+
+	// Below the full height the picker is drawn inline, under the cursor
+	inline := rows < term.Height()
+	if inline {
+		// Make room first, the terminal scrolls when the cursor is near the bottom
+		fmt.Fprint(term, strings.Repeat("\n", rows-1))
+		if rows > 1 {
+			fmt.Fprintf(term, "\033[%dA", rows-1)
+		}
+		fmt.Fprint(term, "\r"+SaveCursorPos)
+		defer fmt.Fprint(term, RestoreCursorPos+ClearToEOS+ShowCursor)
+	} else {
+		term.EnterAltBuffer()
+		defer term.ExitAltBuffer()
+	}
 
 	sel := &selector[T]{
-		items:      displayItems,
-		termHeight: termHeight,
+		formatter:       formatter,
+		multi:           multi,
+		marked:          make(map[int]bool),
+		previewPosition: formatter.PreviewPosition,
+		loading:         true,
+		layout:          layout,
+		inline:          inline,
+	}
+	if layout.Header != "" {
+		sel.header = strings.Split(layout.Header, "\n")
+	}
+	sel.resize(term.Width(), term.Height())
+	sel.query.set(formatter.Query)
+
+	if formatter.History != "" {
+		history, err := LoadHistory(formatter.History)
+		if errors.Is(err, ErrHistoryName) {
+			return Result[T]{}, err
+		}
+		if err != nil {
+			slog.Debug("Failed to load the picker history", "error", err)
+		}
+		sel.history = history
 	}
 
+	var preview *previewer[T]
+	if formatter.Preview != nil {
+		preview = newPreviewer(formatter.Preview)
+		defer preview.stop()
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	incoming := streamItems(items, stop)
+	spinner := time.NewTicker(spinnerInterval)
+	defer spinner.Stop()
+
+	scr := &screen{moveTo: sel.moveTo}
+	events, ctx := term.Events(), term.Context()
 	for {
-		filtered := sel.filter(lookup)
+		filtered := sel.filter()
 		sel.clampSelection(len(filtered))
-		sel.adjustScroll(filtered, lookup)
+		sel.adjustScroll(filtered)
+
+		if preview != nil {
+			sel.requestPreview(preview, filtered)
+		}
 
-		fmt.Print(sel.render(filtered))
+		fmt.Fprint(term, scr.draw(sel.render(filtered)))
 
-		key, r := term.Read()
-		if sel.handleInput(key, r, filtered, lookup) {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return Result[T]{}, fmt.Errorf("terminal closed")
+			}
+			if ev.Key == Resize {
+				if err := term.GetSize(); err != nil {
+					return Result[T]{}, fmt.Errorf("getting terminal size: %w", err)
+				}
+				sel.resize(term.Width(), term.Height())
+				scr.reset()
+				continue
+			}
+			if !sel.handleInput(ev, filtered) {
+				continue
+			}
 			if sel.cancelled {
-				return zero, fmt.Errorf("cancelled")
+				return Result[T]{}, ErrCancelled
 			}
-			return sel.selected, nil
+			if sel.action.Run == nil {
+				sel.remember()
+				return Result[T]{Items: sel.chosen(), Action: sel.action.Name}, nil
+			}
+
+			// The action keeps the picker open with the items it returns
+			reloaded, err := sel.action.Run(sel.items[sel.selected])
+			if err != nil {
+				return Result[T]{}, fmt.Errorf("%s: %w", sel.action.Name, err)
+			}
+			incoming, sel.loading = nil, false
+			sel.reload(reloaded)
+			if preview != nil {
+				preview.key = -1
+			}
+		case res := <-preview.resultsChan():
+			if res.key == preview.key {
+				sel.preview, sel.previewLoading, sel.previewOffset = res.text, false, 0
+			}
+		case item, ok := <-incoming:
+			// Take what else already arrived, a fast source isn't redrawn per item
+			for n := 0; ok && n < streamBatch; n++ {
+				sel.add(item)
+				select {
+				case item, ok = <-incoming:
+				default:
+					n = streamBatch
+				}
+			}
+			if !ok {
+				incoming = nil
+				sel.loading = false
+				spinner.Stop()
+				if len(sel.items) == 0 {
+					return Result[T]{}, errNoItems
+				}
+			}
+		case <-ctx.Done():
+			return Result[T]{}, context.Cause(ctx)
+		case <-spinner.C:
+			sel.spin++
 		}
 	}
 }
 
 type selector[T any] struct {
-	items          []string
-	input          string
-	selectionIndex int
+	formatter      ItemFormatter[T]
+	items          []T
+	rows           []row // Rows of all items in the order they were added
+	lastGroup      string
+	groupItems     int             // Items added for the headers, see ItemFormatter.GroupItem
+	folded         map[string]bool // Groups whose items are hidden
+	query          query
+	selectionIndex int // Index into the filtered rows
 	scrollOffset   int
-	termHeight     int
-	selected       T
+	termHeight     int       // Rows of the list and a preview below it
+	selected       int       // Item chosen with Enter or an action
+	action         Action[T] // Action that closed the picker, zero for Enter
 	cancelled      bool
+	multi          bool         // Several items can be marked
+	marked         map[int]bool // Indexes of the marked items
+	width          int          // Columns inside the border
+
+	layout Layout
+	inline bool     // Drawn under the cursor instead of on the alternate screen
+	height int      // Rows of the terminal the picker takes
+	header []string // Lines of layout.Header
+
+	preview        string // Preview of the current item
+	previewLoading bool   // The preview for the current item is still being computed
+	previewOffset  int    // First preview line shown
+	previewBottom  bool   // Preview below the list instead of to the right
+
+	previewPosition string // As set in the ItemFormatter
+
+	loading bool // Items are still coming in
+	spin    int  // Spinner frame
+
+	matches    map[int]cachedMatch // Matches of the rows against matchQuery
+	matchQuery string
+
+	history *History
+	recall  int // How far back in history.Queries the query was recalled from, 0 when it wasn't
+
+	lastClick   int // Filtered row clicked last, for telling double clicks
+	lastClickAt time.Time
+}
+
+// reload replaces the items of the picker, keeping the query and the
+// position in the list
+func (s *selector[T]) reload(items []T) {
+	s.items, s.rows, s.lastGroup, s.groupItems = nil, nil, "", 0
+	s.matches = nil
+	clear(s.marked)
+	for _, item := range items {
+		s.add(item)
+	}
 }
 
-func (s *selector[T]) filter(lookup map[string]T) []string {
-	if s.input == "" {
-		return s.items
+// add appends item to the picker, after a separator if it starts a new group
+func (s *selector[T]) add(item T) {
+	var group string
+	if s.formatter.GetGroup != nil {
+		group = s.formatter.GetGroup(item)
+		if group != s.lastGroup {
+			if s.lastGroup != "" {
+				s.rows = append(s.rows, row{kind: blankRow, group: group})
+			}
+			// Add custom separator with group name replacement
+			if s.formatter.Separator != "" {
+				sep := strings.ReplaceAll(s.formatter.Separator, "{{GROUP}}", group)
+				header := row{kind: headerRow, text: sep, group: group}
+				if s.formatter.GroupItem != nil {
+					s.items = append(s.items, s.formatter.GroupItem(group))
+					header.item = len(s.items) - 1
+					s.groupItems++
+				}
+				s.rows = append(s.rows, header)
+			}
+			s.lastGroup = group
+		}
 	}
 
-	inputLower := strings.ToLower(s.input)
+	s.items = append(s.items, item)
+	s.rows = append(s.rows, row{
+		kind:  itemRow,
+		text:  s.formatter.ToString(item),
+		group: group,
+		item:  len(s.items) - 1,
+	})
+}
 
-	// Track matching items and their preceding separators
-	filtered := make([]string, 0)
-	pendingSeparators := make([]string, 0) // Store separators until we find a match
-	groupMatches := false                  // Track if the current group header matches
+// selectable reports whether r can be selected: items, and headers when
+// their groups stand for an item
+func (s *selector[T]) selectable(r row) bool {
+	return r.kind == itemRow || r.kind == headerRow && s.formatter.GroupItem != nil
+}
 
-	for _, item := range s.items {
-		// Check if this is a separator (not in lookup)
-		_, isActualItem := lookup[item]
+// cachedMatch remembers how a row matched, so rows that arrive while the
+// query is unchanged are the only ones matched again
+type cachedMatch struct {
+	match Match
+	ok    bool
+}
 
-		if !isActualItem {
+// resize lays the picker out for a terminal of width x height
+func (s *selector[T]) resize(width, height int) {
+	border := 0
+	if s.layout.Border {
+		border = 2
+	}
+	chrome := len(s.header) + 3 // Prompt, header, blank row and counter
 
-			// Check if this separator (group name) matches the filter
-			if item != "" && strings.Contains(strings.ToLower(item), inputLower) {
-				groupMatches = true
-			} else {
-				groupMatches = false
-			}
+	rows, err := s.layout.rows(height)
+	if err != nil {
+		rows = height
+	}
+	if s.inline && s.height > 0 {
+		// Room was only made for the rows taken at the start
+		rows = min(rows, s.height)
+	}
+	s.height = min(height, max(rows, chrome+border+1))
+	s.width = max(1, width-border)
+
+	body := s.innerHeight() - chrome
+	if s.layout.Height == "" {
+		// The full screen picker leaves some room at the bottom
+		body = min(body, max(5, int(float64(height)*0.8)))
+	}
+	s.termHeight = max(1, body)
+	s.previewBottom = s.previewPosition == "bottom" ||
+		s.previewPosition == "" && s.width < 100
+}
+
+// requestPreview asks for the preview of the current item if it changed
+func (s *selector[T]) requestPreview(p *previewer[T], filtered []row) {
+	if s.selectionIndex >= len(filtered) {
+		return
+	}
+	r := filtered[s.selectionIndex]
+	if !s.selectable(r) || r.item == p.key {
+		return
+	}
+	p.request(r.item, s.items[r.item])
+	s.preview, s.previewLoading, s.previewOffset = "", true, 0
+}
+
+// listHeight is the number of rows available to the list
+func (s *selector[T]) listHeight() int {
+	if s.previewBottom && s.previewing() {
+		return max(1, s.termHeight/2)
+	}
+	return s.termHeight
+}
+
+// listWidth is the number of columns available to the list
+func (s *selector[T]) listWidth() int {
+	if s.width <= 0 {
+		return 1 << 16
+	}
+	if s.previewing() && !s.previewBottom {
+		return s.width / 2
+	}
+	return s.width
+}
+
+func (s *selector[T]) previewing() bool {
+	return s.preview != "" || s.previewLoading
+}
+
+// chosen returns the marked items in their original order,
+// or the selected one when nothing is marked.
+func (s *selector[T]) chosen() []T {
+	var items []T
+	for i, item := range s.items {
+		if s.marked[i] {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		items = append(items, s.items[s.selected])
+	}
+	return items
+}
+
+// filter returns the rows matching the query, best matches first.
+// Groups keep their headers and are ordered by their best item, a group
+// whose header matches the query is shown in full. Without a query the
+// items of folded groups are left out.
+func (s *selector[T]) filter() []row {
+	pattern := ParsePattern(s.query.String())
+	if pattern.Empty() {
+		return s.unfolded()
+	}
+	if query := s.query.String(); query != s.matchQuery || s.matches == nil {
+		s.matches, s.matchQuery = make(map[int]cachedMatch), query
+	}
+
+	// Match on the visible text so escape codes in items never match
+	match := func(i int) (Match, bool) {
+		c, seen := s.matches[i]
+		if !seen {
+			c.match, c.ok = pattern.Match(StripANSI(s.rows[i].text))
+			s.matches[i] = c
+		}
+		return c.match, c.ok
+	}
+
+	type scored struct {
+		row   row
+		score int
+	}
+	type group struct {
+		headers []int // Indexes of the header rows
+		items   []int // Indexes of the item rows
+	}
 
-			pendingSeparators = append(pendingSeparators, item)
+	var groups []*group
+	var current *group
+	for i, r := range s.rows {
+		// A run of headers and blanks starts a new group
+		if r.kind != itemRow {
+			if current == nil || len(current.items) > 0 {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			if r.kind == headerRow {
+				current.headers = append(current.headers, i)
+			}
 			continue
 		}
+		if current == nil {
+			current = &group{}
+			groups = append(groups, current)
+		}
+		current.items = append(current.items, i)
+	}
 
-		itemMatches := strings.Contains(strings.ToLower(item), inputLower)
+	type matchedGroup struct {
+		headers []row
+		items   []scored
+		best    int
+	}
 
-		if itemMatches || groupMatches {
+	matched := make([]matchedGroup, 0, len(groups))
+	for _, g := range groups {
+		var headers []row
+		headerScore, headerMatches := 0, false
+		for _, i := range g.headers {
+			h := s.rows[i]
+			if m, ok := match(i); ok {
+				headerScore, headerMatches = max(headerScore, m.Score), true
+				h.positions = m.Positions
+			}
+			headers = append(headers, h)
+		}
 
-			filtered = append(filtered, pendingSeparators...)
-			pendingSeparators = nil
+		var items []scored
+		for _, i := range g.items {
+			it := scored{row: s.rows[i]}
+			if m, ok := match(i); ok {
+				it.score, it.row.positions = m.Score, m.Positions
+			} else if headerMatches {
+				it.score = headerScore
+			} else {
+				continue
+			}
+			if s.history != nil {
+				it.score += s.history.boost(s.historyKey(it.row))
+			}
+			items = append(items, it)
+		}
+		if len(items) == 0 {
+			continue
+		}
 
-			filtered = append(filtered, item)
-		} else {
-			pendingSeparators = nil
+		slices.SortStableFunc(items, func(a, b scored) int { return b.score - a.score })
+		matched = append(matched, matchedGroup{headers: headers, items: items, best: items[0].score})
+	}
+	slices.SortStableFunc(matched, func(a, b matchedGroup) int { return b.best - a.best })
+
+	filtered := make([]row, 0)
+	for i, g := range matched {
+		if i > 0 {
+			filtered = append(filtered, row{kind: blankRow})
+		}
+		filtered = append(filtered, g.headers...)
+		for _, it := range g.items {
+			filtered = append(filtered, it.row)
 		}
 	}
 
 	return filtered
 }
 
+// unfolded returns the rows without the items of folded groups, their
+// headers tell how many are hidden
+func (s *selector[T]) unfolded() []row {
+	if len(s.folded) == 0 {
+		return s.rows
+	}
+	hidden := make(map[string]int)
+	for _, r := range s.rows {
+		if r.kind == itemRow && s.folded[r.group] {
+			hidden[r.group]++
+		}
+	}
+
+	rows := make([]row, 0, len(s.rows))
+	for _, r := range s.rows {
+		switch {
+		case r.kind == itemRow && s.folded[r.group]:
+			continue
+		case r.kind == headerRow && hidden[r.group] > 0:
+			r.text += InColors(colors.muted, fmt.Sprintf(" (%d)", hidden[r.group]))
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
 func (s *selector[T]) clampSelection(max int) {
 	if max == 0 {
 		s.selectionIndex = 0
@@ -164,7 +685,7 @@ func (s *selector[T]) clampSelection(max int) {
 	}
 }
 
-func (s *selector[T]) adjustScroll(filtered []string, lookup map[string]T) {
+func (s *selector[T]) adjustScroll(filtered []row) {
 	if len(filtered) == 0 {
 		s.selectionIndex = 0
 		s.scrollOffset = 0
@@ -179,9 +700,9 @@ func (s *selector[T]) adjustScroll(filtered []string, lookup map[string]T) {
 		s.selectionIndex = len(filtered) - 1
 	}
 
-	// Skip forward over separators (items not in lookup)
+	// Skip forward over headers and blanks
 	for s.selectionIndex < len(filtered) {
-		if _, exists := lookup[filtered[s.selectionIndex]]; exists {
+		if s.selectable(filtered[s.selectionIndex]) {
 			break // Found a valid item
 		}
 		s.selectionIndex++
@@ -191,7 +712,7 @@ func (s *selector[T]) adjustScroll(filtered []string, lookup map[string]T) {
 	if s.selectionIndex >= len(filtered) {
 		s.selectionIndex = len(filtered) - 1
 		for s.selectionIndex >= 0 {
-			if _, exists := lookup[filtered[s.selectionIndex]]; exists {
+			if s.selectable(filtered[s.selectionIndex]) {
 				break // Found a valid item
 			}
 			s.selectionIndex--
@@ -206,8 +727,8 @@ func (s *selector[T]) adjustScroll(filtered []string, lookup map[string]T) {
 	// Adjust scroll to keep selection visible
 	if s.selectionIndex < s.scrollOffset {
 		s.scrollOffset = s.selectionIndex
-	} else if s.selectionIndex >= s.scrollOffset+s.termHeight {
-		s.scrollOffset = s.selectionIndex - s.termHeight + 1
+	} else if s.selectionIndex >= s.scrollOffset+s.listHeight() {
+		s.scrollOffset = s.selectionIndex - s.listHeight() + 1
 	}
 
 	// Ensure scroll offset is valid
@@ -216,89 +737,445 @@ func (s *selector[T]) adjustScroll(filtered []string, lookup map[string]T) {
 	}
 }
 
-func (s *selector[T]) render(filtered []string) string {
-	var buf bytes.Buffer
+func (s *selector[T]) render(filtered []row) *frame {
+	width := s.width
+	if s.layout.Border {
+		width += 2
+	}
+	f := newFrame(width, s.height)
 
-	buf.WriteString(MoveCursorHome + ClearToEOS)
-	buf.WriteString(InColors(Cyan, "> ") + s.input + CRLF + CRLF)
+	prompt, _ := s.layout.prompt()
+	status := InColors(colors.prompt, prompt) + s.query.String()
+	if s.loading {
+		spinner := spinnerFrames[s.spin%len(spinnerFrames)]
+		status += "  " + InColors(colors.muted, fmt.Sprintf("%c %d", spinner, len(s.items)-s.groupItems))
+	}
+	if s.multi && len(s.marked) > 0 {
+		status += "  " + InColors(colors.marker, fmt.Sprintf("(%d selected)", len(s.marked)))
+	}
+	s.put(f, 0, 0, truncateVisible(status, s.width))
+	for i, line := range s.header {
+		row := s.blockRow(1, len(s.header), i)
+		s.put(f, row, 0, truncateVisible(InColors(colors.muted, line), s.width))
+	}
 
+	top := s.listTop()
 	if len(filtered) == 0 {
-		buf.WriteString(InColors(Red, "No results found.") + CRLF)
-		return buf.String()
+		// More items may still match, the spinner tells they are coming
+		if !s.loading {
+			s.put(f, top, 0, InColors(colors.error, "No results found."))
+		}
+		s.renderFrame(f)
+		return f
 	}
 
-	end := min(s.scrollOffset+s.termHeight, len(filtered))
+	listHeight, listWidth := s.listHeight(), s.listWidth()
+	end := min(s.scrollOffset+listHeight, len(filtered))
 	for i := s.scrollOffset; i < end; i++ {
-		// Empty separator line
-		if filtered[i] == "" {
-			buf.WriteString(CRLF)
+		r := filtered[i]
+		if r.kind == blankRow {
 			continue
 		}
 
+		// Shift the matched positions past the two column marker
+		shifted := make([]int, len(r.positions))
+		for j, p := range r.positions {
+			shifted[j] = p + 2
+		}
+
+		mark := " "
+		if r.kind == itemRow && s.marked[r.item] {
+			mark = "+"
+		}
+
+		var line string
 		if i == s.selectionIndex {
-			buf.WriteString(InColors(Blue, "> "+filtered[i]))
+			line = highlightMatches(InColors(colors.pointer, ">"+mark+r.text), shifted, colors.match)
+		} else if mark != " " {
+			line = highlightMatches(InColors(colors.marker, " "+mark)+r.text, shifted, colors.match)
 		} else {
-			buf.WriteString("  " + filtered[i])
+			line = highlightMatches("  "+r.text, shifted, colors.match)
 		}
-		buf.WriteString(CRLF)
+		s.put(f, top+i-s.scrollOffset, 0, truncateVisible(line, listWidth))
 	}
 
-	if len(filtered) > s.termHeight {
-		buf.WriteString(CRLF + InColors(Cyan, fmt.Sprintf("[%d/%d]", end, len(filtered))))
+	if len(filtered) > listHeight {
+		s.put(f, top+listHeight, 0, InColors(colors.prompt, fmt.Sprintf("[%d/%d]", end, len(filtered))))
 	}
 
-	return buf.String()
+	if s.previewing() {
+		s.renderPreview(f, listHeight)
+	}
+
+	s.renderFrame(f)
+	return f
 }
 
-func (s *selector[T]) handleInput(key Key, r rune, filtered []string, lookup map[string]T) bool {
-	switch key {
-	case CtrlC, Escape:
-		s.cancelled = true
-		return true
+// renderFrame finishes a frame with the border and puts the terminal cursor
+// at the query cursor, after the prompt
+func (s *selector[T]) renderFrame(f *frame) {
+	if s.layout.Border {
+		s.renderBorder(f)
+	}
+	_, width := s.layout.prompt()
+	f.showCursor(s.place(0, min(width+s.query.cursorWidth(), s.width)))
+}
 
-	case Backspace:
-		if len(s.input) > 0 {
-			s.input = s.input[:len(s.input)-1]
-			s.selectionIndex, s.scrollOffset = 0, 0
+// renderPreview draws the preview pane over the right half of the list or
+// below it
+func (s *selector[T]) renderPreview(f *frame, listHeight int) {
+	text := s.preview
+	if s.previewLoading {
+		text = InColors(colors.muted, "loading...")
+	}
+
+	top, left := s.listTop(), s.listWidth()
+	width, height := s.width-left-2, listHeight
+	if s.previewBottom {
+		// Under the list and its counter
+		separator := top + listHeight + 1
+		top, left = separator+1, 0
+		width, height = s.width, s.termHeight-listHeight-1
+		s.put(f, separator, 0, InColors(colors.muted, strings.Repeat("─", max(0, s.width))))
+	}
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	lines := previewLines(text)
+	s.previewOffset = max(0, min(s.previewOffset, len(lines)-height))
+	for row := 0; row < height; row++ {
+		var line string
+		if !s.previewBottom {
+			line = InColors(colors.muted, "│") + " "
+		}
+		if i := s.previewOffset + row; i < len(lines) {
+			line += truncateVisible(lines[i], width)
+		}
+		s.put(f, s.blockRow(top, height, row), left, line)
+	}
+}
+
+func (s *selector[T]) handleInput(ev Event, filtered []row) bool {
+	// Shift-Up/Down scroll the preview
+	if ev.Mod&ModShift != 0 {
+		switch ev.Key {
+		case UpArrow:
+			s.previewOffset = max(0, s.previewOffset-1)
+			return false
+		case DownArrow:
+			s.previewOffset++
+			return false
 		}
+	}
 
-	case Enter:
+	// Ctrl-Up/Down jump to the previous or next group
+	if ev.Mod&ModCtrl != 0 && (ev.Key == UpArrow || ev.Key == DownArrow) {
+		step := 1
+		if ev.Key == UpArrow {
+			step = -1
+		}
+		s.jumpGroup(filtered, step)
+		return false
+	}
+
+	// Left/Right fold and unfold groups, they only move the cursor in a query
+	if (ev.Key == LeftArrow || ev.Key == RightArrow) && ev.Mod == 0 && s.foldable() {
+		if ev.Key == LeftArrow {
+			s.fold(filtered)
+		} else {
+			s.unfold(filtered)
+		}
+		return false
+	}
+
+	if action, ok := s.formatter.Actions[ev.Key]; ok && ev.Mod == 0 {
 		if s.selectionIndex < len(filtered) {
-			selectedStr := filtered[s.selectionIndex]
-			// Only return if it's actually in the lookup (not a separator)
-			if item, exists := lookup[selectedStr]; exists {
-				s.selected = item
+			if r := filtered[s.selectionIndex]; s.selectable(r) {
+				s.selected, s.action = r.item, action
 				return true
 			}
 		}
+		return false
+	}
+
+	if (ev.Key == CtrlP || ev.Key == CtrlN) && s.recallQuery(ev.Key == CtrlP) {
+		return false
+	}
 
-	case UpArrow:
-		// Move up, skipping separators (items not in lookup)
-		for i := s.selectionIndex - 1; i >= 0; i-- {
-			if _, exists := lookup[filtered[i]]; exists {
+	switch ev.Key {
+	case CtrlC, Escape:
+		s.cancelled = true
+		return true
+
+	case Tab:
+		if !s.multi || s.selectionIndex >= len(filtered) {
+			break
+		}
+		if r := filtered[s.selectionIndex]; r.kind == itemRow {
+			if s.marked[r.item] {
+				delete(s.marked, r.item)
+			} else {
+				s.marked[r.item] = true
+			}
+		}
+		// Move on to the next item like fzf does
+		for i := s.selectionIndex + 1; i < len(filtered); i++ {
+			if filtered[i].kind == itemRow {
 				s.selectionIndex = i
 				break
 			}
 		}
 
-	case DownArrow:
-		// Move down, skipping separators (items not in lookup)
-		for i := s.selectionIndex + 1; i < len(filtered); i++ {
-			if _, exists := lookup[filtered[i]]; exists {
-				s.selectionIndex = i
+	case CtrlA:
+		// Multi-select keeps Ctrl-A to mark everything, Home still works there
+		if !s.multi {
+			s.query.home()
+			break
+		}
+		// Mark every visible item, or unmark them if they all are marked
+		allMarked := true
+		for _, r := range filtered {
+			if r.kind == itemRow && !s.marked[r.item] {
+				allMarked = false
 				break
 			}
 		}
+		for _, r := range filtered {
+			if r.kind != itemRow {
+				continue
+			}
+			if allMarked {
+				delete(s.marked, r.item)
+			} else {
+				s.marked[r.item] = true
+			}
+		}
 
-	case Other:
-		if r != 0 && !isControlRune(r) {
-			s.input += string(r)
+	case Enter:
+		// Blanks can't be chosen, nor headers without a GroupItem
+		if s.selectionIndex < len(filtered) {
+			if r := filtered[s.selectionIndex]; s.selectable(r) {
+				s.selected, s.action = r.item, Action[T]{}
+				return true
+			}
+		}
+
+	case UpArrow, CtrlP, CtrlK:
+		s.move(filtered, -1)
+
+	case DownArrow, CtrlN, CtrlJ:
+		s.move(filtered, 1)
+
+	case MouseClick, WheelUp, WheelDown:
+		return s.handleMouse(ev, filtered)
+
+	default:
+		// Start over from the top of the list after the query changed
+		if _, changed := s.query.edit(ev); changed {
 			s.selectionIndex, s.scrollOffset = 0, 0
 		}
 	}
 	return false
 }
 
+// move moves the selection to the next item up (step -1) or down (step 1),
+// skipping headers and blanks
+func (s *selector[T]) move(filtered []row, step int) {
+	for i := s.selectionIndex + step; i >= 0 && i < len(filtered); i += step {
+		if s.selectable(filtered[i]) {
+			s.selectionIndex = i
+			return
+		}
+	}
+}
+
+// jumpGroup moves the selection to the first row that can be selected in
+// the group before (step -1) or after (step 1) the current one
+func (s *selector[T]) jumpGroup(filtered []row, step int) {
+	var starts []int // Index of the first row of each group
+	current := -1
+	for i, r := range filtered {
+		if i == 0 || r.group != filtered[i-1].group {
+			starts = append(starts, i)
+		}
+		if i == s.selectionIndex {
+			current = len(starts) - 1
+		}
+	}
+	if current < 0 {
+		return
+	}
+
+	// Folded groups without a selectable header are passed over
+	for g := current + step; g >= 0 && g < len(starts); g += step {
+		group := filtered[starts[g]].group
+		for i := starts[g]; i < len(filtered) && filtered[i].group == group; i++ {
+			if s.selectable(filtered[i]) {
+				s.selectionIndex = i
+				return
+			}
+		}
+	}
+}
+
+// foldable reports whether groups can be folded, they need a header to stay
+// visible and the list isn't filtered
+func (s *selector[T]) foldable() bool {
+	return s.formatter.GetGroup != nil && s.formatter.Separator != "" && s.query.String() == ""
+}
+
+// fold hides the items of the group of the current row and selects its
+// header. Without a GroupItem the selection goes on to the next group.
+func (s *selector[T]) fold(filtered []row) {
+	header := groupHeader(filtered, s.selectionIndex)
+	if header < 0 {
+		return
+	}
+	if s.folded == nil {
+		s.folded = make(map[string]bool)
+	}
+	// Rows above the header stay, so does its index in the folded list
+	s.folded[filtered[header].group] = true
+	s.selectionIndex = header
+}
+
+// unfold shows the items of the group of the current row again, or of the
+// group above it, which the selection skips when it was folded
+func (s *selector[T]) unfold(filtered []row) {
+	header := groupHeader(filtered, s.selectionIndex)
+	if header < 0 {
+		return
+	}
+	if !s.folded[filtered[header].group] {
+		if header = groupHeader(filtered, header-1); header < 0 || !s.folded[filtered[header].group] {
+			return
+		}
+	}
+	delete(s.folded, filtered[header].group)
+	s.selectionIndex = header
+}
+
+// groupHeader returns the index of the header of the group row i is in,
+// -1 if there is none
+func groupHeader(filtered []row, i int) int {
+	for ; i >= 0 && i < len(filtered); i-- {
+		if filtered[i].kind == headerRow {
+			return i
+		}
+	}
+	return -1
+}
+
+// handleMouse selects the clicked item and accepts it on a double click.
+// The wheel moves through the list, or scrolls the preview under it.
+func (s *selector[T]) handleMouse(ev Event, filtered []row) bool {
+	if ev.Key == WheelUp || ev.Key == WheelDown {
+		step := 1
+		if ev.Key == WheelUp {
+			step = -1
+		}
+		if r, c, ok := s.cell(ev.X, ev.Y); ok && s.overPreview(r, c) {
+			s.previewOffset = max(0, s.previewOffset+step*wheelLines)
+		} else {
+			s.move(filtered, step)
+		}
+		return false
+	}
+
+	r, c, ok := s.cell(ev.X, ev.Y)
+	line := r - s.listTop()
+	i := s.scrollOffset + line
+	if !ok || line < 0 || line >= s.listHeight() || i >= len(filtered) ||
+		c >= s.listWidth() || !s.selectable(filtered[i]) {
+		return false
+	}
+
+	double := i == s.lastClick && time.Since(s.lastClickAt) < doubleClick
+	s.selectionIndex, s.lastClick, s.lastClickAt = i, i, time.Now()
+	if double {
+		s.selected, s.action = filtered[i].item, Action[T]{}
+		return true
+	}
+	return false
+}
+
+// overPreview reports whether row r and column c of the picker are in the
+// preview pane
+func (s *selector[T]) overPreview(r, c int) bool {
+	if !s.previewing() {
+		return false
+	}
+	if s.previewBottom {
+		return r > s.listTop()+s.listHeight()
+	}
+	return r >= s.listTop() && c >= s.listWidth()
+}
+
+// recallQuery replaces the query with an older or newer one from the
+// history. It only does so while the prompt is empty or shows a recalled
+// query, and reports whether it did, otherwise Ctrl-P/Ctrl-N move in the list.
+func (s *selector[T]) recallQuery(older bool) bool {
+	if s.history == nil || len(s.history.Queries) == 0 {
+		return false
+	}
+	queries := s.history.Queries
+	switch query := s.query.String(); {
+	case query == "":
+		s.recall = 0
+	case s.recall == 0 || s.recall > len(queries) || queries[len(queries)-s.recall] != query:
+		return false
+	}
+
+	switch {
+	case older && s.recall < len(queries):
+		s.recall++
+	case older:
+		return true // Already at the oldest
+	case s.recall == 0:
+		return false
+	default:
+		s.recall--
+	}
+
+	if s.recall == 0 {
+		s.query.set("")
+	} else {
+		s.query.set(queries[len(queries)-s.recall])
+	}
+	s.selectionIndex, s.scrollOffset = 0, 0
+	return true
+}
+
+// historyKey returns what the history remembers the item of r by
+func (s *selector[T]) historyKey(r row) string {
+	if s.formatter.HistoryKey != nil {
+		return s.formatter.HistoryKey(s.items[r.item])
+	}
+	return StripANSI(r.text)
+}
+
+// remember records the query and the chosen items in the history
+func (s *selector[T]) remember() {
+	if s.history == nil {
+		return
+	}
+	var keys []string
+	for _, item := range s.chosen() {
+		key := StripANSI(s.formatter.ToString(item))
+		if s.formatter.HistoryKey != nil {
+			key = s.formatter.HistoryKey(item)
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	s.history.Add(s.query.String(), keys...)
+	if err := s.history.Save(); err != nil {
+		slog.Debug("Failed to save the picker history", "error", err)
+	}
+}
+
 func isControlRune(r rune) bool {
 	return r < 32 || r == 127
 }
@@ -0,0 +1,459 @@
+package busybox
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSelector returns a selector over items shown as they are
+func newTestSelector(items ...string) *selector[string] {
+	sel := &selector[string]{
+		formatter:  ItemFormatter[string]{ToString: func(s string) string { return s }},
+		termHeight: 10,
+		marked:     make(map[int]bool),
+	}
+	for _, item := range items {
+		sel.add(item)
+	}
+	return sel
+}
+
+// rowTexts returns the text of rows, "" for blank rows
+func rowTexts(rows []row) []string {
+	texts := make([]string, 0, len(rows))
+	for _, r := range rows {
+		texts = append(texts, r.text)
+	}
+	return texts
+}
+
+// press sends key to sel the way the picker loop does
+func press(sel *selector[string], key Key) bool {
+	filtered := sel.filter()
+	sel.adjustScroll(filtered)
+	return sel.handleInput(Event{Key: key}, filtered)
+}
+
+func TestSelectorMultiSelect(t *testing.T) {
+	sel := newTestSelector("tn", "fd", "fs", "gcat")
+	sel.multi = true
+	press := func(key Key) bool { return press(sel, key) }
+
+	// Tab marks and moves down, so this marks tn and fs
+	press(Tab)
+	press(DownArrow)
+	press(Tab)
+	if press(Enter) != true {
+		t.Fatal("Expected Enter to finish the selection")
+	}
+	if got, want := sel.chosen(), []string{"tn", "fs"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Ctrl-A marks every visible item, a second time unmarks them
+	sel.query.set("f")
+	press(CtrlA)
+	if got, want := sel.chosen(), []string{"tn", "fd", "fs"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	press(CtrlA)
+	if got, want := sel.chosen(), []string{"tn"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestSelectorActions(t *testing.T) {
+	sel := newTestSelector("tn", "fd", "fs")
+	sel.formatter.Actions = map[Key]Action[string]{
+		CtrlX: {Name: "kill"},
+	}
+
+	press(sel, DownArrow)
+	if !press(sel, CtrlX) {
+		t.Fatal("Expected Ctrl-X to close the picker")
+	}
+	if sel.action.Name != "kill" || sel.items[sel.selected] != "fd" {
+		t.Errorf("Expected kill on fd, got %q on %q", sel.action.Name, sel.items[sel.selected])
+	}
+
+	// Alt-X is not the action and is not typed either
+	if sel.handleInput(Event{Key: CtrlX, Mod: ModAlt}, sel.filter()) {
+		t.Error("Expected Alt-Ctrl-X to be ignored")
+	}
+
+	// Enter after an action reports no action
+	press(sel, Enter)
+	if sel.action.Name != "" {
+		t.Errorf("Expected no action after Enter, got %q", sel.action.Name)
+	}
+
+	// Reloading keeps the query and drops the marks of the old items
+	sel.multi = true
+	sel.query.set("f")
+	press(sel, Tab)
+	sel.reload([]string{"tn", "fs"})
+	if got, want := rowTexts(sel.filter()), []string{"fs"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if len(sel.marked) != 0 {
+		t.Errorf("Expected no marks after reload, got %v", sel.marked)
+	}
+}
+
+func TestSelectorMouse(t *testing.T) {
+	sel := newTestSelector("tn", "fd", "fs", "gcat")
+	sel.previewPosition = "right"
+	sel.resize(80, 20)
+	send := func(key Key, x, y int) bool {
+		filtered := sel.filter()
+		sel.adjustScroll(filtered)
+		return sel.handleInput(Event{Key: key, X: x, Y: y}, filtered)
+	}
+
+	// The list starts on the third row
+	if send(MouseClick, 3, 4) || sel.selectionIndex != 1 {
+		t.Errorf("Expected a click to select fd, got selection %d", sel.selectionIndex)
+	}
+	if send(MouseClick, 3, 5) || sel.selectionIndex != 2 {
+		t.Errorf("Expected a click on another item to select it, got selection %d", sel.selectionIndex)
+	}
+	if !send(MouseClick, 3, 5) || sel.items[sel.selected] != "fs" {
+		t.Errorf("Expected a double click to accept fs, got %q", sel.items[sel.selected])
+	}
+
+	sel.lastClickAt = time.Time{}
+	if send(MouseClick, 3, 1) || send(MouseClick, 3, 7) || sel.selectionIndex != 2 {
+		t.Errorf("Expected clicks off the list to be ignored, got selection %d", sel.selectionIndex)
+	}
+
+	send(WheelUp, 3, 4)
+	send(WheelUp, 3, 4)
+	if sel.selectionIndex != 0 {
+		t.Errorf("Expected the wheel to move up, got selection %d", sel.selectionIndex)
+	}
+
+	// Over the preview the wheel scrolls it
+	sel.preview = "cat.go\nfzf.go"
+	send(WheelDown, 60, 4)
+	if sel.previewOffset != wheelLines || sel.selectionIndex != 0 {
+		t.Errorf("Expected the preview to scroll, got offset %d and selection %d", sel.previewOffset, sel.selectionIndex)
+	}
+}
+
+func TestSelectorResize(t *testing.T) {
+	testCases := []struct {
+		position   string
+		width      int
+		height     int
+		termHeight int
+		bottom     bool
+	}{
+		{position: "", width: 120, height: 50, termHeight: 40, bottom: false},
+		{position: "", width: 80, height: 50, termHeight: 40, bottom: true},
+		{position: "right", width: 80, height: 4, termHeight: 1, bottom: false},
+		{position: "bottom", width: 200, height: 10, termHeight: 7, bottom: true},
+	}
+
+	for _, tc := range testCases {
+		sel := &selector[int]{previewPosition: tc.position}
+		sel.resize(tc.width, tc.height)
+		if sel.width != tc.width || sel.termHeight != tc.termHeight || sel.previewBottom != tc.bottom {
+			t.Errorf("Expected %dx%d bottom=%v for %q at %dx%d, got %dx%d bottom=%v",
+				tc.width, tc.termHeight, tc.bottom, tc.position, tc.width, tc.height,
+				sel.width, sel.termHeight, sel.previewBottom)
+		}
+	}
+}
+
+// [Filter] ranks items like the picker does, without a terminal
+func ExampleFilter() {
+	files := []string{"README.md", "cmd/pick.go", "main.go", "Makefile"}
+	formatter := ItemFormatter[string]{ToString: func(s string) string { return s }}
+
+	fmt.Println(Filter(files, formatter, "go$"))
+	fmt.Println(Filter(files, formatter, "^ma"))
+	fmt.Println(Filter(files, formatter, "^Ma"))
+	// Output:
+	// [cmd/pick.go main.go]
+	// [main.go Makefile]
+	// [Makefile]
+}
+
+func TestStreamItems(t *testing.T) {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := range 3 {
+			ch <- i
+		}
+	}()
+
+	var got []int
+	for item := range streamItems(FromChan(ch), make(chan struct{})) {
+		got = append(got, item)
+	}
+	if want := []int{0, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Closing stop ends an endless source
+	yielded := make(chan struct{})
+	endless := func(yield func(int) bool) {
+		defer close(yielded)
+		for i := 0; yield(i); i++ {
+		}
+	}
+	stop := make(chan struct{})
+	items := streamItems(endless, stop)
+	<-items
+	close(stop)
+	select {
+	case <-yielded:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the source to stop")
+	}
+}
+
+func TestSelectorFilterStreaming(t *testing.T) {
+	sel := newTestSelector()
+	sel.query.set("fd")
+
+	filter := func() []string {
+		return rowTexts(sel.filter())
+	}
+
+	sel.add("tn")
+	sel.add("fs")
+	if got := filter(); len(got) != 0 {
+		t.Errorf("Expected no match, got %v", got)
+	}
+
+	// Rows coming in later are matched with the same query
+	sel.add("fd")
+	sel.add("find")
+	if got, want := filter(), []string{"fd", "find"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if len(sel.matches) != 4 {
+		t.Errorf("Expected 4 cached matches, got %d", len(sel.matches))
+	}
+
+	sel.query.set("tn")
+	if got, want := filter(), []string{"tn"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestSelectorDuplicates(t *testing.T) {
+	type window struct {
+		session string
+		name    string
+		index   int
+	}
+	windows := []window{
+		{"gosh", "gosh", 1},
+		{"gosh", "main", 2},
+		{"notes", "main", 1},
+		{"notes", "gosh", 2},
+	}
+	formatter := ItemFormatter[window]{
+		ToString:  func(w window) string { return w.name },
+		GetGroup:  func(w window) string { return w.session },
+		Separator: "{{GROUP}}", // Headers read the same as some windows
+	}
+	newSel := func() *selector[window] {
+		sel := &selector[window]{formatter: formatter, termHeight: 20, marked: make(map[int]bool)}
+		for _, w := range windows {
+			sel.add(w)
+		}
+		return sel
+	}
+	press := func(sel *selector[window], key Key) bool {
+		filtered := sel.filter()
+		sel.adjustScroll(filtered)
+		return sel.handleInput(Event{Key: key}, filtered)
+	}
+
+	t.Run("every item is listed", func(t *testing.T) {
+		sel := newSel()
+		want := []string{"gosh", "gosh", "main", "", "notes", "main", "gosh"}
+		if got := rowTexts(sel.filter()); !slices.Equal(got, want) {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	})
+
+	t.Run("headers are skipped even when they read like an item", func(t *testing.T) {
+		sel := newSel()
+		press(sel, DownArrow)
+		press(sel, DownArrow)
+		if !press(sel, Enter) {
+			t.Fatal("Expected Enter to choose an item")
+		}
+		if got, want := sel.chosen(), []window{windows[2]}; !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("the chosen duplicate is returned", func(t *testing.T) {
+		sel := newSel()
+		sel.query.set("main")
+		press(sel, DownArrow)
+		if !press(sel, Enter) {
+			t.Fatal("Expected Enter to choose an item")
+		}
+		if got, want := sel.chosen(), []window{windows[2]}; !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("marking one duplicate leaves the other", func(t *testing.T) {
+		sel := newSel()
+		sel.multi = true
+		sel.query.set("main")
+		press(sel, Tab)
+		if got, want := sel.chosen(), []window{windows[1]}; !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("filter keeps duplicates", func(t *testing.T) {
+		got := Filter(windows, formatter, "main")
+		if want := []window{windows[1], windows[2]}; !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+}
+
+func TestSelectorGroups(t *testing.T) {
+	newSel := func(groupItem bool) *selector[string] {
+		sel := newTestSelector()
+		sel.formatter.GetGroup = func(s string) string { return strings.Split(s, ":")[0] }
+		sel.formatter.Separator = "{{GROUP}}"
+		if groupItem {
+			sel.formatter.GroupItem = func(group string) string { return group + ":" }
+		}
+		for _, item := range []string{"dev:editor", "dev:shell", "ops:server", "ops:notes", "web:app"} {
+			sel.add(item)
+		}
+		return sel
+	}
+	send := func(sel *selector[string], ev Event) {
+		filtered := sel.filter()
+		sel.adjustScroll(filtered)
+		sel.handleInput(ev, filtered)
+		sel.adjustScroll(sel.filter())
+	}
+	current := func(sel *selector[string]) string {
+		return sel.filter()[sel.selectionIndex].text
+	}
+
+	t.Run("jump between groups", func(t *testing.T) {
+		sel := newSel(false)
+		send(sel, Event{Key: DownArrow, Mod: ModCtrl})
+		if got := current(sel); got != "ops:server" {
+			t.Errorf("Expected the first item of the next group, got %q", got)
+		}
+		send(sel, Event{Key: DownArrow, Mod: ModCtrl})
+		send(sel, Event{Key: DownArrow, Mod: ModCtrl})
+		if got := current(sel); got != "web:app" {
+			t.Errorf("Expected to stay in the last group, got %q", got)
+		}
+		send(sel, Event{Key: UpArrow, Mod: ModCtrl})
+		if got := current(sel); got != "ops:server" {
+			t.Errorf("Expected the previous group, got %q", got)
+		}
+	})
+
+	t.Run("fold and unfold", func(t *testing.T) {
+		sel := newSel(false)
+		send(sel, Event{Key: DownArrow})
+		send(sel, Event{Key: LeftArrow})
+		want := []string{"dev" + InColors(colors.muted, " (2)"), "", "ops", "ops:server", "ops:notes", "", "web", "web:app"}
+		if got := rowTexts(sel.filter()); !slices.Equal(got, want) {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+		if got := current(sel); got != "ops:server" {
+			t.Errorf("Expected the selection to go on to the next group, got %q", got)
+		}
+
+		// A query shows everything and Left moves the cursor
+		sel.query.set("dev")
+		if got := len(sel.filter()); got != 3 {
+			t.Errorf("Expected the folded group to be searched, got %d rows", got)
+		}
+		send(sel, Event{Key: LeftArrow})
+		if sel.query.cursor != 2 {
+			t.Errorf("Expected Left to move the cursor, got %d", sel.query.cursor)
+		}
+		sel.query.set("")
+
+		send(sel, Event{Key: RightArrow})
+		if got := len(sel.filter()); got != 10 {
+			t.Errorf("Expected the group above to be unfolded, got %d rows", got)
+		}
+		if got := current(sel); got != "dev:editor" {
+			t.Errorf("Expected the unfolded group to be selected, got %q", got)
+		}
+	})
+
+	t.Run("headers stand for their group", func(t *testing.T) {
+		sel := newSel(true)
+		if got := current(sel); got != "dev" {
+			t.Errorf("Expected the header to be selectable, got %q", got)
+		}
+		send(sel, Event{Key: DownArrow, Mod: ModCtrl})
+		send(sel, Event{Key: DownArrow})
+		send(sel, Event{Key: LeftArrow})
+		if got := current(sel); got != "ops"+InColors(colors.muted, " (2)") {
+			t.Errorf("Expected the folded header to be selected, got %q", got)
+		}
+		if !press(sel, Enter) {
+			t.Fatal("Expected Enter to choose the header")
+		}
+		if got, want := sel.chosen(), []string{"ops:"}; !slices.Equal(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		if got, want := Filter([]string{"dev:editor", "ops:server", "ops:notes"}, sel.formatter, "ops"), []string{"ops:server", "ops:notes"}; !slices.Equal(got, want) {
+			t.Errorf("Expected Filter to leave headers out, %v, got %v", want, got)
+		}
+	})
+}
+
+func TestPreviewerCancels(t *testing.T) {
+	started, cancelled := make(chan string), make(chan string, 1)
+	p := newPreviewer(func(ctx context.Context, item string) string {
+		if item == "slow" {
+			started <- item
+			<-ctx.Done()
+			cancelled <- item
+		}
+		return "preview of " + item
+	})
+	defer p.stop()
+
+	p.request(0, "slow")
+	<-started
+	p.request(1, "fast")
+
+	select {
+	case got := <-cancelled:
+		if got != "slow" {
+			t.Errorf("Expected the slow preview to be cancelled, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stale preview to be cancelled when the selection moved")
+	}
+
+	select {
+	case res := <-p.resultsChan():
+		if res.key != 1 || res.text != "preview of fast" {
+			t.Errorf("Expected only the newest preview, got %+v", res)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the newest preview")
+	}
+}
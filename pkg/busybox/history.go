@@ -0,0 +1,101 @@
+package busybox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/DnFreddie/gosh/pkg/frecency"
+)
+
+const (
+	maxQueries = 100 // Queries kept per history, the oldest are dropped
+	maxBoost   = 4   // Recent choices that count towards the boost of an item
+	boostUnit  = 100 // Frecency of an item chosen once in the last hours
+)
+
+// History remembers the queries typed in a picker and the items chosen in
+// it. The picker recalls the queries with Ctrl-P/Ctrl-N and ranks matching
+// items higher by how often and how recently they were chosen. Items are
+// remembered by their text, or by [ItemFormatter.HistoryKey].
+type History struct {
+	path    string
+	Queries []string       `json:"queries"`  // Oldest first, each only once
+	Chosen  frecency.Store `json:"frecency"` // When each item was chosen
+}
+
+// StateDir returns the gosh state directory,
+// $XDG_STATE_HOME/gosh or ~/.local/state/gosh.
+func StateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "gosh")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "gosh")
+}
+
+// ErrHistoryName is returned by [LoadHistory] for a name that isn't a plain
+// file name, like one that would point outside of the history directory
+var ErrHistoryName = errors.New("invalid history name")
+
+// LoadHistory reads the history kept under name in [StateDir]. A history
+// that doesn't exist yet is empty. On error the history is empty too and
+// can still be used and saved, except for an invalid name where it is nil.
+func LoadHistory(name string) (*History, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return nil, fmt.Errorf("%w %q", ErrHistoryName, name)
+	}
+	h := &History{path: filepath.Join(StateDir(), "history", name+".json")}
+
+	data, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, fmt.Errorf("reading history %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		h.Queries, h.Chosen = nil, frecency.Store{}
+		return h, fmt.Errorf("parsing history %s: %w", h.path, err)
+	}
+	return h, nil
+}
+
+// Add records a query and the items chosen with it
+func (h *History) Add(query string, chosen ...string) {
+	if query != "" {
+		h.Queries = slices.DeleteFunc(h.Queries, func(q string) bool { return q == query })
+		h.Queries = append(h.Queries, query)
+		if len(h.Queries) > maxQueries {
+			h.Queries = slices.Clone(h.Queries[len(h.Queries)-maxQueries:])
+		}
+	}
+
+	for _, item := range chosen {
+		h.Chosen.Add(item)
+	}
+}
+
+// Save writes the history back to where it was loaded from, see
+// [frecency.WriteJSON] for two pickers closing at once
+func (h *History) Save() error {
+	if err := frecency.WriteJSON(h.path, h); err != nil {
+		return fmt.Errorf("saving history: %w", err)
+	}
+	return nil
+}
+
+// boost is added to the score of a matching item chosen before. Any item
+// chosen counts, the ones chosen often and lately the most.
+func (h *History) boost(text string) int {
+	if h == nil {
+		return 0
+	}
+	units := math.Ceil(h.Chosen.Score(text) / boostUnit)
+	return scoreMatch * min(int(units), maxBoost)
+}
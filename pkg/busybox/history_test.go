@@ -0,0 +1,157 @@
+package busybox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	h, err := LoadHistory("tn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Queries) != 0 || len(h.Chosen.Entries) != 0 {
+		t.Fatalf("Expected an empty history, got %+v", h)
+	}
+
+	h.Add("dev", "dev")
+	h.Add("ops", "ops")
+	h.Add("", "dev")
+	h.Add("dev", "dev")
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err = LoadHistory("tn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ops", "dev"}; !slices.Equal(h.Queries, want) {
+		t.Errorf("Expected queries %q, got %q", want, h.Queries)
+	}
+	if want := []string{"dev", "ops"}; !slices.Equal(h.Chosen.Keys(), want) {
+		t.Errorf("Expected dev ranked before ops, got %q", h.Chosen.Keys())
+	}
+	if h.Chosen.Entries["dev"].Count != 3 || h.Chosen.Entries["ops"].Count != 1 {
+		t.Errorf("Expected dev chosen 3 times and ops once, got %v", h.Chosen.Entries)
+	}
+
+	// A broken file is reported, the history still works
+	path := filepath.Join(StateDir(), "history", "fd.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h, err = LoadHistory("fd")
+	if err == nil {
+		t.Error("Expected an error for a broken history")
+	}
+	h.Add("gosh", "gosh")
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryName(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	for _, name := range []string{"../../x", "a/b", `..\x`, "..", ""} {
+		h, err := LoadHistory(name)
+		if !errors.Is(err, ErrHistoryName) || h != nil {
+			t.Errorf("Expected %q to be rejected, got %v", name, err)
+		}
+	}
+
+	if _, err := LoadHistory("tn-windows"); err != nil {
+		t.Errorf("Expected a plain name to be accepted, got %v", err)
+	}
+}
+
+func TestHistoryLimits(t *testing.T) {
+	h := &History{}
+	for i := range maxQueries + 10 {
+		h.Add(string(rune('a'+i%26)) + string(rune('a'+i/26)))
+	}
+	if len(h.Queries) != maxQueries || h.Queries[0] != "ka" {
+		t.Errorf("Expected the %d newest queries from ka, got %d from %q", maxQueries, len(h.Queries), h.Queries[0])
+	}
+}
+
+func TestSelectorRecall(t *testing.T) {
+	sel := newTestSelector("dev", "ops", "notes")
+	sel.history = &History{Queries: []string{"dev", "ops"}}
+
+	testCases := []struct {
+		key       Key
+		query     string
+		selection int
+	}{
+		{key: CtrlP, query: "ops"},
+		{key: CtrlP, query: "dev"},
+		{key: CtrlP, query: "dev"}, // The oldest one
+		{key: CtrlN, query: "ops"},
+		{key: CtrlN, query: ""},
+		{key: CtrlN, query: "", selection: 1}, // Nothing newer, moves down
+		{key: CtrlP, query: "ops"},
+	}
+	for _, tc := range testCases {
+		press(sel, tc.key)
+		if sel.query.String() != tc.query || sel.selectionIndex != tc.selection {
+			t.Errorf("Expected query %q and selection %d after key %d, got %q and %d",
+				tc.query, tc.selection, tc.key, sel.query.String(), sel.selectionIndex)
+		}
+	}
+
+	// Once the query is edited the keys move in the list again
+	sel.query.set("o")
+	press(sel, CtrlN)
+	if sel.query.String() != "o" || sel.selectionIndex != 1 {
+		t.Errorf("Expected Ctrl-N to move down, got query %q and selection %d", sel.query.String(), sel.selectionIndex)
+	}
+}
+
+func TestSelectorHistoryBoost(t *testing.T) {
+	sel := newTestSelector("fd.go", "dotfiles/fd")
+	sel.query.set("fd")
+	if got := rowTexts(sel.filter()); got[0] != "fd.go" {
+		t.Fatalf("Expected the match at the start first, got %q", got)
+	}
+
+	sel.history = &History{}
+	sel.history.Add("", "dotfiles/fd", "dotfiles/fd")
+	if got := rowTexts(sel.filter()); got[0] != "dotfiles/fd" {
+		t.Errorf("Expected the item chosen before first, got %q", got)
+	}
+
+	// The list without a query keeps its order
+	sel.query.set("")
+	if got := rowTexts(sel.filter()); got[0] != "fd.go" {
+		t.Errorf("Expected the original order, got %q", got)
+	}
+}
+
+func TestSelectorHistoryKey(t *testing.T) {
+	// Windows named the same in two sessions, remembered by both names
+	sel := &selector[string]{
+		formatter: ItemFormatter[string]{
+			ToString:   func(s string) string { return s[strings.Index(s, ":")+1:] },
+			HistoryKey: func(s string) string { return s },
+		},
+		termHeight: 10,
+		marked:     make(map[int]bool),
+	}
+	sel.add("dev:editor")
+	sel.add("ops:editor")
+
+	sel.history = &History{}
+	sel.history.Add("", "ops:editor")
+	sel.query.set("editor")
+	if got := sel.items[sel.filter()[0].item]; got != "ops:editor" {
+		t.Errorf("Expected the window chosen before first, got %q", got)
+	}
+}
@@ -0,0 +1,273 @@
+package busybox
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// escTimeout is how long a lone Escape waits for the rest of a sequence
+// before it counts as the Escape key
+const escTimeout = 25 * time.Millisecond
+
+// maxSequence bounds the length of an escape sequence, anything longer
+// without a final byte is dropped as garbage
+const maxSequence = 64
+
+// ctrlKeys maps the control bytes 0x01-0x1a to keys
+var ctrlKeys = [...]Key{
+	1: CtrlA, 2: CtrlB, 3: CtrlC, 4: CtrlD, 5: CtrlE, 6: CtrlF, 7: CtrlG,
+	8: Backspace, 9: Tab, 10: CtrlJ, 11: CtrlK, 12: CtrlL, 13: Enter,
+	14: CtrlN, 15: CtrlO, 16: CtrlP, 17: CtrlQ, 18: CtrlR, 19: CtrlS,
+	20: CtrlT, 21: CtrlU, 22: CtrlV, 23: CtrlW, 24: CtrlX, 25: CtrlY, 26: CtrlZ,
+}
+
+// tildeKeys maps the first parameter of CSI ... ~ sequences to keys
+var tildeKeys = map[int]Key{
+	1: Home, 2: Insert, 3: Delete, 4: End, 5: PageUp, 6: PageDown, 7: Home, 8: End,
+	11: F1, 12: F2, 13: F3, 14: F4, 15: F5, 17: F6, 18: F7, 19: F8,
+	20: F9, 21: F10, 23: F11, 24: F12,
+}
+
+// finalKeys maps the final byte of CSI and SS3 sequences to keys
+var finalKeys = map[byte]Key{
+	'A': UpArrow, 'B': DownArrow, 'C': RightArrow, 'D': LeftArrow,
+	'H': Home, 'F': End, 'Z': BackTab,
+	'P': F1, 'Q': F2, 'R': F3, 'S': F4,
+}
+
+// decodeLoop decodes the chunks read from the terminal into events until
+// chunks is closed or done. An incomplete sequence waits timeout for the rest
+// of it, so a lone Escape is told apart from the start of an arrow key.
+func decodeLoop(chunks <-chan []byte, events chan<- Event, done <-chan struct{}, timeout time.Duration) {
+	defer close(events)
+
+	send := func(ev Event) bool {
+		select {
+		case events <- ev:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	var pending []byte
+	for {
+		for len(pending) > 0 {
+			ev, n := decodeEvent(pending, false)
+			if n == 0 {
+				break
+			}
+			pending = pending[n:]
+			if !send(ev) {
+				return
+			}
+		}
+
+		var expired <-chan time.Time
+		if len(pending) > 0 {
+			expired = time.After(timeout)
+		}
+
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				for len(pending) > 0 {
+					ev, n := decodeEvent(pending, true)
+					pending = pending[n:]
+					if !send(ev) {
+						return
+					}
+				}
+				return
+			}
+			pending = append(pending, chunk...)
+		case <-expired:
+			ev, n := decodeEvent(pending, true)
+			pending = pending[n:]
+			if !send(ev) {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// decodeEvent decodes the event at the start of b and returns it with the
+// number of bytes it used. When b ends in the middle of a sequence it returns
+// 0, unless final is set: then no more input is coming and whatever b starts
+// with is decoded on its own, a lone ESC being the Escape key.
+func decodeEvent(b []byte, final bool) (Event, int) {
+	if len(b) == 0 {
+		return Event{}, 0
+	}
+
+	switch c := b[0]; {
+	case c == 0x1b:
+		return decodeEscape(b, final)
+	case c == 0x7f:
+		return Event{Key: Backspace}, 1
+	case c == 0:
+		return Event{Key: Other, Rune: ' ', Mod: ModCtrl}, 1
+	case int(c) < len(ctrlKeys):
+		return Event{Key: ctrlKeys[c]}, 1
+	case c < 0x20:
+		return Event{Key: Unknown}, 1
+	}
+
+	if !utf8.FullRune(b) {
+		if final {
+			return Event{Key: Unknown}, 1
+		}
+		return Event{}, 0
+	}
+	r, size := utf8.DecodeRune(b)
+	if r == utf8.RuneError && size == 1 {
+		return Event{Key: Unknown}, 1
+	}
+	return Event{Key: Other, Rune: r}, size
+}
+
+// decodeEscape decodes b starting with ESC: a CSI or SS3 sequence, Alt with
+// another key, or Escape itself
+func decodeEscape(b []byte, final bool) (Event, int) {
+	if len(b) == 1 {
+		if final {
+			return Event{Key: Escape}, 1
+		}
+		return Event{}, 0
+	}
+
+	switch b[1] {
+	case '[':
+		ev, n := decodeCSI(b)
+		if n == 0 && final {
+			return Event{Key: Escape}, 1
+		}
+		return ev, n
+	case 'O':
+		if len(b) == 2 {
+			if final {
+				return Event{Key: Other, Rune: 'O', Mod: ModAlt}, 2
+			}
+			return Event{}, 0
+		}
+		if key, ok := finalKeys[b[2]]; ok {
+			return Event{Key: key}, 3
+		}
+		return Event{Key: Unknown}, 3
+	case 0x1b:
+		return Event{Key: Escape}, 1
+	}
+
+	// ESC followed by a key is how terminals send Alt with that key
+	ev, n := decodeEvent(b[1:], final)
+	if n == 0 {
+		return ev, 0
+	}
+	ev.Mod |= ModAlt
+	return ev, n + 1
+}
+
+// decodeCSI decodes b starting with ESC [, returning 0 if the sequence has not
+// ended yet
+func decodeCSI(b []byte) (Event, int) {
+	end := 2
+	for end < len(b) && b[end] >= 0x20 && b[end] <= 0x3f {
+		end++
+	}
+	if end == len(b) {
+		if end >= maxSequence {
+			return Event{Key: Unknown}, end
+		}
+		return Event{}, 0
+	}
+	if b[end] < 0x40 || b[end] > 0x7e {
+		// Not a valid sequence, drop the introducer only
+		return Event{Key: Unknown}, 2
+	}
+
+	n := end + 1
+	if b[2] == '<' {
+		return decodeMouse(string(b[3:end]), b[end]), n
+	}
+
+	params := strings.Split(string(b[2:end]), ";")
+
+	var mod Mod
+	if len(params) > 1 {
+		mod = modifier(params[1])
+	}
+
+	if b[end] == '~' {
+		code, _ := strconv.Atoi(params[0])
+		if key, ok := tildeKeys[code]; ok {
+			return Event{Key: key, Mod: mod}, n
+		}
+		return Event{Key: Unknown}, n
+	}
+	if key, ok := finalKeys[b[end]]; ok {
+		if key == BackTab {
+			mod = 0
+		}
+		return Event{Key: key, Mod: mod}, n
+	}
+	return Event{Key: Unknown}, n
+}
+
+// decodeMouse decodes the parameters of an SGR mouse sequence, ESC [ <
+// button ; x ; y followed by M for a press or m for a release. Only left
+// clicks and the wheel are reported, anything else is Unknown.
+func decodeMouse(params string, final byte) Event {
+	var n [3]int
+	parts := strings.Split(params, ";")
+	if len(parts) != len(n) {
+		return Event{Key: Unknown}
+	}
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return Event{Key: Unknown}
+		}
+		n[i] = v
+	}
+
+	button := n[0]
+	ev := Event{X: n[1], Y: n[2]}
+	if button&4 != 0 {
+		ev.Mod |= ModShift
+	}
+	if button&8 != 0 {
+		ev.Mod |= ModAlt
+	}
+	if button&16 != 0 {
+		ev.Mod |= ModCtrl
+	}
+
+	switch {
+	case final != 'M' || button&32 != 0:
+		// Releases and drags
+		return Event{Key: Unknown}
+	case button&64 != 0 && button&3 == 0:
+		ev.Key = WheelUp
+	case button&64 != 0 && button&3 == 1:
+		ev.Key = WheelDown
+	case button&(64|3) == 0:
+		ev.Key = MouseClick
+	default:
+		return Event{Key: Unknown}
+	}
+	return ev
+}
+
+// modifier decodes the xterm modifier parameter, 1 plus a bit set of
+// shift (1), alt (2) and ctrl (4)
+func modifier(param string) Mod {
+	m, err := strconv.Atoi(param)
+	if err != nil || m < 1 {
+		return 0
+	}
+	return Mod(m-1) & (ModShift | ModAlt | ModCtrl)
+}
@@ -0,0 +1,160 @@
+package busybox
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		final bool
+		want  Event
+		n     int
+	}{
+		{name: "ascii", input: "a", want: Event{Key: Other, Rune: 'a'}, n: 1},
+		{name: "two byte utf-8", input: "ł", want: Event{Key: Other, Rune: 'ł'}, n: 2},
+		{name: "three byte utf-8", input: "€x", want: Event{Key: Other, Rune: '€'}, n: 3},
+		{name: "four byte utf-8", input: "🙂", want: Event{Key: Other, Rune: '🙂'}, n: 4},
+		{name: "partial utf-8 waits", input: "\xc5", n: 0},
+		{name: "partial utf-8 at the end", input: "\xc5", final: true, want: Event{Key: Unknown}, n: 1},
+		{name: "invalid utf-8", input: "\xff", want: Event{Key: Unknown}, n: 1},
+
+		{name: "enter", input: "\r", want: Event{Key: Enter}, n: 1},
+		{name: "ctrl-j", input: "\n", want: Event{Key: CtrlJ}, n: 1},
+		{name: "tab", input: "\t", want: Event{Key: Tab}, n: 1},
+		{name: "backspace", input: "\x7f", want: Event{Key: Backspace}, n: 1},
+		{name: "ctrl-h", input: "\x08", want: Event{Key: Backspace}, n: 1},
+		{name: "ctrl-a", input: "\x01", want: Event{Key: CtrlA}, n: 1},
+		{name: "ctrl-c", input: "\x03", want: Event{Key: CtrlC}, n: 1},
+		{name: "ctrl-w", input: "\x17", want: Event{Key: CtrlW}, n: 1},
+		{name: "ctrl-space", input: "\x00", want: Event{Key: Other, Rune: ' ', Mod: ModCtrl}, n: 1},
+
+		{name: "lone escape waits", input: "\x1b", n: 0},
+		{name: "lone escape at the end", input: "\x1b", final: true, want: Event{Key: Escape}, n: 1},
+		{name: "double escape", input: "\x1b\x1b", want: Event{Key: Escape}, n: 1},
+		{name: "alt-x", input: "\x1bx", want: Event{Key: Other, Rune: 'x', Mod: ModAlt}, n: 2},
+		{name: "alt-ł", input: "\x1bł", want: Event{Key: Other, Rune: 'ł', Mod: ModAlt}, n: 3},
+		{name: "alt-backspace", input: "\x1b\x7f", want: Event{Key: Backspace, Mod: ModAlt}, n: 2},
+
+		{name: "up", input: "\x1b[A", want: Event{Key: UpArrow}, n: 3},
+		{name: "down", input: "\x1b[B", want: Event{Key: DownArrow}, n: 3},
+		{name: "right", input: "\x1b[C", want: Event{Key: RightArrow}, n: 3},
+		{name: "left", input: "\x1b[D", want: Event{Key: LeftArrow}, n: 3},
+		{name: "home", input: "\x1b[H", want: Event{Key: Home}, n: 3},
+		{name: "end", input: "\x1b[F", want: Event{Key: End}, n: 3},
+		{name: "home vt", input: "\x1b[1~", want: Event{Key: Home}, n: 4},
+		{name: "end rxvt", input: "\x1b[8~", want: Event{Key: End}, n: 4},
+		{name: "page up", input: "\x1b[5~", want: Event{Key: PageUp}, n: 4},
+		{name: "page down", input: "\x1b[6~", want: Event{Key: PageDown}, n: 4},
+		{name: "delete", input: "\x1b[3~", want: Event{Key: Delete}, n: 4},
+		{name: "insert", input: "\x1b[2~", want: Event{Key: Insert}, n: 4},
+		{name: "f5", input: "\x1b[15~", want: Event{Key: F5}, n: 5},
+		{name: "f12", input: "\x1b[24~", want: Event{Key: F12}, n: 5},
+		{name: "shift-tab", input: "\x1b[Z", want: Event{Key: BackTab}, n: 3},
+		{name: "shift-up", input: "\x1b[1;2A", want: Event{Key: UpArrow, Mod: ModShift}, n: 6},
+		{name: "ctrl-down", input: "\x1b[1;5B", want: Event{Key: DownArrow, Mod: ModCtrl}, n: 6},
+		{name: "ctrl-alt-left", input: "\x1b[1;7D", want: Event{Key: LeftArrow, Mod: ModCtrl | ModAlt}, n: 6},
+		{name: "ctrl-delete", input: "\x1b[3;5~", want: Event{Key: Delete, Mod: ModCtrl}, n: 6},
+		{name: "unknown csi", input: "\x1b[99~x", want: Event{Key: Unknown}, n: 5},
+		{name: "partial csi waits", input: "\x1b[1;5", n: 0},
+		{name: "partial csi at the end", input: "\x1b[1;5", final: true, want: Event{Key: Escape}, n: 1},
+
+		{name: "ss3 up", input: "\x1bOA", want: Event{Key: UpArrow}, n: 3},
+		{name: "ss3 home", input: "\x1bOH", want: Event{Key: Home}, n: 3},
+		{name: "ss3 f1", input: "\x1bOP", want: Event{Key: F1}, n: 3},
+		{name: "partial ss3 waits", input: "\x1bO", n: 0},
+		{name: "alt-O at the end", input: "\x1bO", final: true, want: Event{Key: Other, Rune: 'O', Mod: ModAlt}, n: 2},
+
+		{name: "click", input: "\x1b[<0;12;5M", want: Event{Key: MouseClick, X: 12, Y: 5}, n: 10},
+		{name: "ctrl-click", input: "\x1b[<16;1;140M", want: Event{Key: MouseClick, Mod: ModCtrl, X: 1, Y: 140}, n: 12},
+		{name: "release", input: "\x1b[<0;12;5m", want: Event{Key: Unknown}, n: 10},
+		{name: "drag", input: "\x1b[<32;12;5M", want: Event{Key: Unknown}, n: 11},
+		{name: "right click", input: "\x1b[<2;12;5M", want: Event{Key: Unknown}, n: 10},
+		{name: "wheel up", input: "\x1b[<64;3;4M", want: Event{Key: WheelUp, X: 3, Y: 4}, n: 10},
+		{name: "wheel down", input: "\x1b[<65;3;4M", want: Event{Key: WheelDown, X: 3, Y: 4}, n: 10},
+		{name: "broken mouse", input: "\x1b[<65;3M", want: Event{Key: Unknown}, n: 8},
+		{name: "partial mouse waits", input: "\x1b[<0;12", n: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, n := decodeEvent([]byte(tc.input), tc.final)
+			if n != tc.n {
+				t.Fatalf("Expected %d bytes used, got %d", tc.n, n)
+			}
+			if n > 0 && got != tc.want {
+				t.Errorf("Expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeLoop(t *testing.T) {
+	testCases := []struct {
+		name   string
+		chunks []string
+		want   []Event
+	}{
+		{
+			name:   "several keys in one read",
+			chunks: []string{"gł\x1b[A\r"},
+			want: []Event{
+				{Key: Other, Rune: 'g'}, {Key: Other, Rune: 'ł'}, {Key: UpArrow}, {Key: Enter},
+			},
+		},
+		{
+			name:   "sequence split across reads",
+			chunks: []string{"\x1b", "[1;2", "B"},
+			want:   []Event{{Key: DownArrow, Mod: ModShift}},
+		},
+		{
+			name:   "rune split across reads",
+			chunks: []string{"\xc5", "\x82"},
+			want:   []Event{{Key: Other, Rune: 'ł'}},
+		},
+		{
+			name:   "escape on its own",
+			chunks: []string{"\x1b", ""},
+			want:   []Event{{Key: Escape}},
+		},
+		{
+			name:   "escape then a key",
+			chunks: []string{"\x1b", "", "q"},
+			want:   []Event{{Key: Escape}, {Key: Other, Rune: 'q'}},
+		},
+	}
+
+	const timeout = 20 * time.Millisecond
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chunks := make(chan []byte)
+			events := make(chan Event, 16)
+			done := make(chan struct{})
+			defer close(done)
+
+			go decodeLoop(chunks, events, done, timeout)
+
+			// An empty chunk stands for a pause longer than the timeout
+			for _, c := range tc.chunks {
+				if c == "" {
+					time.Sleep(3 * timeout)
+					continue
+				}
+				chunks <- []byte(c)
+			}
+			close(chunks)
+
+			var got []Event
+			for ev := range events {
+				got = append(got, ev)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
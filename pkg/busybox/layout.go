@@ -0,0 +1,150 @@
+package busybox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Escape codes for drawing inline, relative to where the picker started
+const (
+	SaveCursorPos    = "\0337" // Remember the cursor position
+	RestoreCursorPos = "\0338" // Move back to the remembered position
+)
+
+// Layout is how the picker is placed and drawn
+type Layout struct {
+	// Rows the picker takes, a count like "15" or a share of the terminal
+	// like "40%". Less than the whole terminal draws the picker inline under
+	// the cursor instead of on the alternate screen. Empty takes the screen.
+	Height string
+
+	Reverse bool   // Prompt at the bottom with the list growing up from it
+	Border  bool   // Box around the picker
+	Prompt  string // Shown before the query, "> " by default
+	Header  string // Lines shown next to the prompt that can't be chosen
+}
+
+// rows returns how many rows of a terminal height rows tall the picker takes
+func (l Layout) rows(height int) (int, error) {
+	if l.Height == "" {
+		return height, nil
+	}
+
+	var n int
+	if percent, ok := strings.CutSuffix(l.Height, "%"); ok {
+		p, err := strconv.Atoi(percent)
+		if err != nil || p <= 0 || p > 100 {
+			return 0, fmt.Errorf("invalid height %q, expected a row count or 1%% to 100%%", l.Height)
+		}
+		n = height * p / 100
+	} else {
+		var err error
+		n, err = strconv.Atoi(l.Height)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid height %q, expected a row count or 1%% to 100%%", l.Height)
+		}
+	}
+	return min(n, height), nil
+}
+
+// prompt returns the prompt and how many columns it takes
+func (l Layout) prompt() (string, int) {
+	prompt := l.Prompt
+	if prompt == "" {
+		prompt = "> "
+	}
+	return prompt, visibleWidth(prompt)
+}
+
+// The rows of the picker from the top, inside the border: the prompt, the
+// header lines, a blank row, then the list and its counter. With Reverse the
+// same rows count from the bottom.
+
+// innerHeight is the number of rows inside the border
+func (s *selector[T]) innerHeight() int {
+	if s.layout.Border {
+		return max(0, s.height-2)
+	}
+	return s.height
+}
+
+// listTop is the row of the first list line
+func (s *selector[T]) listTop() int {
+	return len(s.header) + 2
+}
+
+// place returns the row and column of the frame for row r and column c of
+// the picker, 0 based and inside the border
+func (s *selector[T]) place(r, c int) (int, int) {
+	if s.layout.Reverse {
+		r = s.innerHeight() - 1 - r
+	}
+	if s.layout.Border {
+		r, c = r+1, c+1
+	}
+	return r, c
+}
+
+// put draws text at row r and column c of the picker, see [selector.place]
+func (s *selector[T]) put(f *frame, r, c int, text string) {
+	row, col := s.place(r, c)
+	f.put(row, col, text)
+}
+
+// blockRow is the row of the k-th of n lines starting at row top that are
+// read top to bottom in either layout, like a preview or the header
+func (s *selector[T]) blockRow(top, n, k int) int {
+	if s.layout.Reverse {
+		return top + n - 1 - k
+	}
+	return top + k
+}
+
+// moveTo moves the cursor to row r and column c of the frame, the area the
+// picker takes, 0 based. Inline that area starts at the saved cursor position.
+func (s *selector[T]) moveTo(r, c int) string {
+	if !s.inline {
+		return MoveCursorTo(r+1, c+1)
+	}
+	seq := RestoreCursorPos
+	if r > 0 {
+		seq += fmt.Sprintf("\033[%dB", r)
+	}
+	if c > 0 {
+		seq += fmt.Sprintf("\033[%dC", c)
+	}
+	return seq
+}
+
+// cell returns the row and column of the picker, as used by [selector.place],
+// at the 1 based screen position x, y. It reports false off the picker and
+// when drawing inline, the screen row the picker starts on isn't known then.
+func (s *selector[T]) cell(x, y int) (r, c int, ok bool) {
+	if s.inline {
+		return 0, 0, false
+	}
+	r, c = y-1, x-1
+	if s.layout.Border {
+		r, c = r-1, c-1
+	}
+	if r < 0 || r >= s.innerHeight() || c < 0 || c >= s.width {
+		return 0, 0, false
+	}
+	if s.layout.Reverse {
+		r = s.innerHeight() - 1 - r
+	}
+	return r, c, true
+}
+
+// renderBorder draws the box around the picker
+func (s *selector[T]) renderBorder(f *frame) {
+	height := s.innerHeight()
+	line := strings.Repeat("─", s.width)
+	f.put(0, 0, InColors(colors.muted, "╭"+line+"╮"))
+	for r := 1; r <= height; r++ {
+		f.put(r, 0, InColors(colors.muted, "│"))
+		f.put(r, s.width+1, InColors(colors.muted, "│"))
+	}
+	f.put(height+1, 0, InColors(colors.muted, "╰"+line+"╯"))
+}
@@ -0,0 +1,319 @@
+package busybox
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Scoring follows fzf: every matched rune is worth scoreMatch, gaps cost
+// points and runes at word boundaries, after path separators or in a
+// consecutive run earn a bonus.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	bonusBoundary          = scoreMatch / 2
+	bonusNonWord           = scoreMatch / 2
+	bonusCamel123          = bonusBoundary + scoreGapExtension
+	bonusConsecutive       = -(scoreGapStart + scoreGapExtension)
+	bonusBoundaryWhite     = bonusBoundary + 2
+	bonusBoundaryDelimiter = bonusBoundary + 1
+	bonusFirstCharFactor   = 2
+)
+
+type charClass int
+
+const (
+	charWhite charClass = iota
+	charNonWord
+	charDelimiter
+	charLower
+	charUpper
+	charLetter
+	charNumber
+)
+
+func classOf(r rune) charClass {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return charLower
+	case r >= 'A' && r <= 'Z':
+		return charUpper
+	case r >= '0' && r <= '9':
+		return charNumber
+	case unicode.IsSpace(r):
+		return charWhite
+	case strings.ContainsRune("/,:;|", r):
+		return charDelimiter
+	case unicode.IsLower(r):
+		return charLower
+	case unicode.IsUpper(r):
+		return charUpper
+	case unicode.IsLetter(r):
+		return charLetter
+	case unicode.IsNumber(r):
+		return charNumber
+	}
+	return charNonWord
+}
+
+// bonusFor is the bonus of a rune of class cur following one of class prev
+func bonusFor(prev, cur charClass) int {
+	if cur > charNonWord {
+		switch prev {
+		case charWhite:
+			return bonusBoundaryWhite
+		case charDelimiter:
+			return bonusBoundaryDelimiter
+		case charNonWord:
+			return bonusBoundary
+		}
+	}
+	if prev == charLower && cur == charUpper || prev != charNumber && cur == charNumber {
+		return bonusCamel123
+	}
+	switch cur {
+	case charNonWord, charDelimiter:
+		return bonusNonWord
+	case charWhite:
+		return bonusBoundaryWhite
+	}
+	return 0
+}
+
+// Match is the result of matching a [Pattern] against a text
+type Match struct {
+	Score     int
+	Positions []int // Indexes of the matched runes, sorted
+}
+
+type termKind int
+
+const (
+	termFuzzy termKind = iota
+	termExact
+	termPrefix
+	termSuffix
+	termEqual
+)
+
+type queryTerm struct {
+	kind    termKind
+	text    []rune
+	inverse bool
+}
+
+// Pattern is a parsed picker query. Terms are separated by spaces and
+// an item has to satisfy all of them:
+//
+//	gshss    fuzzy match
+//	'exact   substring match
+//	^prefix  match at the start
+//	suffix$  match at the end
+//	!negate  item must not match, combines with ', ^ and $
+//
+// Matching ignores case unless the query contains an upper case letter.
+type Pattern struct {
+	terms         []queryTerm
+	caseSensitive bool
+}
+
+// ParsePattern parses a query typed into the picker
+func ParsePattern(query string) Pattern {
+	p := Pattern{
+		caseSensitive: strings.IndexFunc(query, unicode.IsUpper) >= 0,
+	}
+
+	for _, field := range strings.Fields(query) {
+		t := queryTerm{kind: termFuzzy}
+
+		if rest, ok := strings.CutPrefix(field, "!"); ok && rest != "" {
+			t.inverse = true
+			t.kind = termExact
+			field = rest
+		}
+
+		switch {
+		case strings.HasPrefix(field, "'") && len(field) > 1:
+			t.kind = termExact
+			field = field[1:]
+		case strings.HasPrefix(field, "^") && strings.HasSuffix(field, "$") && len(field) > 2:
+			t.kind = termEqual
+			field = field[1 : len(field)-1]
+		case strings.HasPrefix(field, "^") && len(field) > 1:
+			t.kind = termPrefix
+			field = field[1:]
+		case strings.HasSuffix(field, "$") && len(field) > 1:
+			t.kind = termSuffix
+			field = field[:len(field)-1]
+		}
+
+		if !p.caseSensitive {
+			field = strings.ToLower(field)
+		}
+		t.text = []rune(field)
+		p.terms = append(p.terms, t)
+	}
+	return p
+}
+
+// Empty reports whether the pattern matches everything
+func (p Pattern) Empty() bool {
+	return len(p.terms) == 0
+}
+
+// Match reports whether text satisfies every term of the pattern and how well
+func (p Pattern) Match(text string) (Match, bool) {
+	var m Match
+	if p.Empty() {
+		return m, true
+	}
+
+	runes := []rune(text)
+	folded := runes
+	if !p.caseSensitive {
+		folded = make([]rune, len(runes))
+		for i, r := range runes {
+			folded[i] = unicode.ToLower(r)
+		}
+	}
+
+	for _, t := range p.terms {
+		tm, ok := t.match(runes, folded)
+		if t.inverse {
+			if ok {
+				return Match{}, false
+			}
+			continue
+		}
+		if !ok {
+			return Match{}, false
+		}
+		m.Score += tm.Score
+		m.Positions = append(m.Positions, tm.Positions...)
+	}
+
+	slices.Sort(m.Positions)
+	m.Positions = slices.Compact(m.Positions)
+	return m, true
+}
+
+func (t queryTerm) match(runes, folded []rune) (Match, bool) {
+	n := len(t.text)
+	switch t.kind {
+	case termFuzzy:
+		return fuzzyMatch(runes, folded, t.text)
+	case termExact:
+		best, found := Match{}, false
+		for i := 0; i+n <= len(folded); i++ {
+			if !slices.Equal(folded[i:i+n], t.text) {
+				continue
+			}
+			if m := score(runes, folded, t.text, i, i+n); !found || m.Score > best.Score {
+				best, found = m, true
+			}
+		}
+		return best, found
+	case termPrefix:
+		if n <= len(folded) && slices.Equal(folded[:n], t.text) {
+			return score(runes, folded, t.text, 0, n), true
+		}
+	case termSuffix:
+		if n <= len(folded) && slices.Equal(folded[len(folded)-n:], t.text) {
+			return score(runes, folded, t.text, len(folded)-n, len(folded)), true
+		}
+	case termEqual:
+		if slices.Equal(folded, t.text) {
+			return score(runes, folded, t.text, 0, n), true
+		}
+	}
+	return Match{}, false
+}
+
+// fuzzyMatch finds pattern as a subsequence of folded. A forward scan finds
+// the first window ending in a full match, a backward scan from its end
+// then shrinks the window so the match is as tight as possible.
+func fuzzyMatch(runes, folded, pattern []rune) (Match, bool) {
+	if len(pattern) == 0 {
+		return Match{}, true
+	}
+
+	pidx, end := 0, -1
+	for i, r := range folded {
+		if r == pattern[pidx] {
+			pidx++
+			if pidx == len(pattern) {
+				end = i + 1
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return Match{}, false
+	}
+
+	pidx, start := len(pattern)-1, 0
+	for i := end - 1; i >= 0; i-- {
+		if folded[i] == pattern[pidx] {
+			pidx--
+			if pidx < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	return score(runes, folded, pattern, start, end), true
+}
+
+// score walks the window [start, end) matching pattern greedily and adds up
+// the points for matched runes, gaps and bonuses.
+func score(runes, folded, pattern []rune, start, end int) Match {
+	m := Match{Positions: make([]int, 0, len(pattern))}
+
+	prevClass := charWhite
+	if start > 0 {
+		prevClass = classOf(runes[start-1])
+	}
+
+	pidx, consecutive, firstBonus := 0, 0, 0
+	inGap := false
+	for i := start; i < end; i++ {
+		class := classOf(runes[i])
+		if pidx < len(pattern) && folded[i] == pattern[pidx] {
+			m.Positions = append(m.Positions, i)
+			m.Score += scoreMatch
+
+			bonus := bonusFor(prevClass, class)
+			if consecutive == 0 {
+				firstBonus = bonus
+			} else {
+				if bonus >= bonusBoundary && bonus > firstBonus {
+					firstBonus = bonus
+				}
+				bonus = max(bonus, firstBonus, bonusConsecutive)
+			}
+
+			if pidx == 0 {
+				m.Score += bonus * bonusFirstCharFactor
+			} else {
+				m.Score += bonus
+			}
+			inGap = false
+			consecutive++
+			pidx++
+		} else {
+			if inGap {
+				m.Score += scoreGapExtension
+			} else {
+				m.Score += scoreGapStart
+			}
+			inGap = true
+			consecutive, firstBonus = 0, 0
+		}
+		prevClass = class
+	}
+	return m
+}
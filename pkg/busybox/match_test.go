@@ -0,0 +1,123 @@
+package busybox
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	testCases := []struct {
+		query       string
+		text        string
+		shouldMatch bool
+	}{
+		{"gshss", "gosh/sessionizer", true},
+		{"gshss", "gosh", false},
+		{"GOSH", "gosh", false}, // Upper case makes the query case sensitive
+		{"gosh", "GoSh", true},
+		{"'sess", "gosh/sessionizer", true},
+		{"'ssn", "gosh/sessionizer", false},
+		{"^gosh", "gosh/sessionizer", true},
+		{"^sess", "gosh/sessionizer", false},
+		{"izer$", "gosh/sessionizer", true},
+		{"gosh$", "gosh/sessionizer", false},
+		{"^gosh$", "gosh", true},
+		{"^gosh$", "gosh/", false},
+		{"!node", "project/node_modules", false},
+		{"!node", "project/src", true},
+		{"!^src", "src/main.go", false},
+		{"!^src", "cmd/src", true},
+		{"pro !node", "project/src", true},
+		{"pro !node", "project/node_modules", false},
+		{"żł", "zażółć", true},
+		{"", "anything", true},
+	}
+
+	for _, tc := range testCases {
+		_, ok := ParsePattern(tc.query).Match(tc.text)
+		if ok != tc.shouldMatch {
+			t.Errorf("Expected match of %q against %q: %v, but got %v", tc.query, tc.text, tc.shouldMatch, ok)
+		}
+	}
+}
+
+func TestPatternScore(t *testing.T) {
+	// Each pair is (better, worse) for the same query
+	testCases := []struct {
+		query  string
+		better string
+		worse  string
+	}{
+		{"gshss", "gosh/sessionizer", "go-hash-sum-files"},    // path separators and boundaries
+		{"fzf", "pkg/fzf.go", "fuzzy/zfs"},                    // consecutive run
+		{"tn", "cmd/tn_cmd.go", "internal/sessionizer"},       // word start
+		{"mdc", "my_dotfiles_config", "mydotfilesconfig"},     // underscores are boundaries
+		{"sc", "SessionConfig", "sessionconfig"},              // camel case
+		{"dot", "~/.dotfiles", "~/projects/dashboard_tool"},   // tighter match
+		{"'conf", "config/confd", "deconfigure"},              // exact at a boundary
+		{"gosh", "gosh", "github.com/DnFreddie/gosh-archive"}, // first char bonus
+	}
+
+	for _, tc := range testCases {
+		p := ParsePattern(tc.query)
+		better, ok := p.Match(tc.better)
+		if !ok {
+			t.Errorf("Expected %q to match %q", tc.query, tc.better)
+			continue
+		}
+		worse, ok := p.Match(tc.worse)
+		if !ok {
+			t.Errorf("Expected %q to match %q", tc.query, tc.worse)
+			continue
+		}
+		if better.Score <= worse.Score {
+			t.Errorf("Expected %q to score %q (%d) above %q (%d)", tc.query, tc.better, better.Score, tc.worse, worse.Score)
+		}
+	}
+}
+
+// [Pattern.Match] reports the matched rune positions for highlighting
+func ExamplePattern_Match() {
+	m, ok := ParsePattern("gshss").Match("gosh/sessionizer")
+	fmt.Println(ok, m.Positions)
+
+	m, ok = ParsePattern("^go izer$").Match("gosh/sessionizer")
+	fmt.Println(ok, m.Positions)
+	// Output:
+	// true [0 2 3 5 7]
+	// true [0 1 12 13 14 15]
+}
+
+func TestSelectorFilterGroups(t *testing.T) {
+	type window struct{ session, name string }
+	sel := &selector[window]{formatter: ItemFormatter[window]{
+		ToString:  func(w window) string { return w.name },
+		GetGroup:  func(w window) string { return w.session },
+		Separator: "● {{GROUP}}",
+	}}
+	for _, w := range []window{
+		{"dev", "editor"}, {"dev", "shell"}, {"dev", "sessionizer"},
+		{"ops", "server"}, {"ops", "notes"},
+	} {
+		sel.add(w)
+	}
+
+	sel.query.set("sess")
+	if got, want := rowTexts(sel.filter()), []string{"● dev", "sessionizer"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// The header matches, so the whole group is shown
+	sel.query.set("ops")
+	if got, want := rowTexts(sel.filter()), []string{"● ops", "server", "notes"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Groups are ordered by their best item, items by score
+	sel.query.set("sr")
+	want := []string{"● ops", "server", "", "● dev", "sessionizer"}
+	if got := rowTexts(sel.filter()); !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
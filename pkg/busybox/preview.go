@@ -0,0 +1,79 @@
+package busybox
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// previewDelay lets the selection settle before a preview is computed,
+// so scrolling quickly through the list doesn't start a job per row.
+const previewDelay = 30 * time.Millisecond
+
+type previewResult struct {
+	key  int
+	text string
+}
+
+// previewer computes previews off the main loop. Only the newest request
+// counts, the context of older ones is cancelled and their results dropped.
+type previewer[T any] struct {
+	fn      func(context.Context, T) string
+	results chan previewResult
+	cancel  context.CancelFunc
+	key     int // Item the latest request was made for, -1 before the first
+}
+
+func newPreviewer[T any](fn func(context.Context, T) string) *previewer[T] {
+	return &previewer[T]{
+		fn:      fn,
+		results: make(chan previewResult),
+		key:     -1,
+	}
+}
+
+// request starts computing the preview of item, cancelling the previous one
+func (p *previewer[T]) request(key int, item T) {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel, p.key = cancel, key
+
+	go func() {
+		select {
+		case <-time.After(previewDelay):
+		case <-ctx.Done():
+			return
+		}
+
+		text := p.fn(ctx, item)
+
+		select {
+		case p.results <- previewResult{key: key, text: text}:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// resultsChan is nil, and so never ready, when there is no previewer
+func (p *previewer[T]) resultsChan() <-chan previewResult {
+	if p == nil {
+		return nil
+	}
+	return p.results
+}
+
+func (p *previewer[T]) stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// previewLines splits a preview into lines, expanding tabs so the
+// pane's columns stay where they are drawn
+func previewLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\t", "    ")
+	return styledLines(strings.TrimRight(text, "\n"))
+}
@@ -0,0 +1,57 @@
+package busybox
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReadLine asks for a line of text on the terminal, starting with initial.
+// The line is edited like the query of the picker. Enter returns it, Escape
+// and Ctrl-C return [ErrCancelled].
+func ReadLine(prompt string, initial string) (string, error) {
+	term, err := NewTerm()
+	if err != nil {
+		return "", err
+	}
+	defer term.Close()
+
+	var q query
+	q.set(initial)
+
+	events, ctx := term.Events(), term.Context()
+	for {
+		fmt.Fprint(term, renderLine(prompt, &q))
+
+		var ev Event
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return "", fmt.Errorf("terminal closed")
+			}
+			ev = e
+		case <-ctx.Done():
+			fmt.Fprint(term, CRLF)
+			return "", context.Cause(ctx)
+		}
+		switch ev.Key {
+		case Enter:
+			fmt.Fprint(term, CRLF)
+			return q.String(), nil
+		case CtrlC, Escape:
+			fmt.Fprint(term, CRLF)
+			return "", ErrCancelled
+		default:
+			q.edit(ev)
+		}
+	}
+}
+
+// renderLine redraws the current line with the prompt and q, leaving the
+// cursor at the cursor of q
+func renderLine(prompt string, q *query) string {
+	line := ResetCursor + InColors(colors.prompt, prompt) + q.String() + ClearToEOL + ResetCursor
+	if col := visibleWidth(prompt) + q.cursorWidth(); col > 0 {
+		line += fmt.Sprintf("\033[%dC", col)
+	}
+	return line + ShowCursor
+}
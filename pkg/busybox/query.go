@@ -0,0 +1,132 @@
+package busybox
+
+import (
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// query is the editable line the picker filters with. It is kept as runes so
+// the cursor and deletions never split a multibyte character.
+type query struct {
+	runes  []rune
+	cursor int // Index into runes the next rune is inserted at
+}
+
+func (q *query) String() string {
+	return string(q.runes)
+}
+
+// cursorWidth returns how many terminal columns the runes before the cursor take
+func (q *query) cursorWidth() int {
+	return runewidth.StringWidth(string(q.runes[:q.cursor]))
+}
+
+// set replaces the query with s and moves the cursor to its end
+func (q *query) set(s string) {
+	q.runes = []rune(s)
+	q.cursor = len(q.runes)
+}
+
+// insert adds r at the cursor
+func (q *query) insert(r rune) {
+	q.runes = append(q.runes[:q.cursor], append([]rune{r}, q.runes[q.cursor:]...)...)
+	q.cursor++
+}
+
+// backspace deletes the rune before the cursor and reports if there was one
+func (q *query) backspace() bool {
+	if q.cursor == 0 {
+		return false
+	}
+	q.runes = append(q.runes[:q.cursor-1], q.runes[q.cursor:]...)
+	q.cursor--
+	return true
+}
+
+// deleteForward deletes the rune under the cursor and reports if there was one
+func (q *query) deleteForward() bool {
+	if q.cursor == len(q.runes) {
+		return false
+	}
+	q.runes = append(q.runes[:q.cursor], q.runes[q.cursor+1:]...)
+	return true
+}
+
+// deleteWord deletes the word before the cursor along with the spaces
+// between it and the cursor, like Ctrl-W in a shell
+func (q *query) deleteWord() bool {
+	start := q.cursor
+	for start > 0 && unicode.IsSpace(q.runes[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(q.runes[start-1]) {
+		start--
+	}
+	if start == q.cursor {
+		return false
+	}
+	q.runes = append(q.runes[:start], q.runes[q.cursor:]...)
+	q.cursor = start
+	return true
+}
+
+// clear empties the query and reports if it had anything in it
+func (q *query) clear() bool {
+	if len(q.runes) == 0 {
+		return false
+	}
+	q.runes, q.cursor = q.runes[:0], 0
+	return true
+}
+
+func (q *query) left() {
+	q.cursor = max(0, q.cursor-1)
+}
+
+func (q *query) right() {
+	q.cursor = min(len(q.runes), q.cursor+1)
+}
+
+func (q *query) home() {
+	q.cursor = 0
+}
+
+func (q *query) end() {
+	q.cursor = len(q.runes)
+}
+
+// edit applies the line editing keys in ev to the query. It reports whether
+// ev was one of them and whether the text changed.
+func (q *query) edit(ev Event) (handled bool, changed bool) {
+	switch ev.Key {
+	case Backspace:
+		if ev.Mod&ModAlt != 0 {
+			return true, q.deleteWord()
+		}
+		return true, q.backspace()
+	case Delete:
+		return true, q.deleteForward()
+	case CtrlW:
+		return true, q.deleteWord()
+	case CtrlU:
+		return true, q.clear()
+	case LeftArrow, CtrlB:
+		q.left()
+	case RightArrow, CtrlF:
+		q.right()
+	case Home, CtrlA:
+		q.home()
+	case End, CtrlE:
+		q.end()
+	case Other:
+		if ev.Mod&(ModAlt|ModCtrl) != 0 || isControlRune(ev.Rune) {
+			return false, false
+		}
+		q.insert(ev.Rune)
+		return true, true
+	default:
+		return false, false
+	}
+	return true, false
+}
@@ -0,0 +1,139 @@
+package busybox
+
+import "testing"
+
+func TestSelectorQueryEditing(t *testing.T) {
+	typed := func(s string) []Event {
+		var events []Event
+		for _, r := range s {
+			events = append(events, Event{Key: Other, Rune: r})
+		}
+		return events
+	}
+	keys := func(keys ...Key) []Event {
+		var events []Event
+		for _, k := range keys {
+			events = append(events, Event{Key: k})
+		}
+		return events
+	}
+	concat := func(parts ...[]Event) []Event {
+		var events []Event
+		for _, p := range parts {
+			events = append(events, p...)
+		}
+		return events
+	}
+
+	testCases := []struct {
+		name   string
+		events []Event
+		want   string
+		cursor int
+	}{
+		{
+			name:   "typing multibyte runes",
+			events: typed("żółw"),
+			want:   "żółw",
+			cursor: 4,
+		},
+		{
+			name:   "backspace removes a whole rune",
+			events: concat(typed("gęś"), keys(Backspace)),
+			want:   "gę",
+			cursor: 2,
+		},
+		{
+			name:   "insert in the middle",
+			events: concat(typed("gh"), keys(LeftArrow), typed("os")),
+			want:   "gosh",
+			cursor: 3,
+		},
+		{
+			name:   "home and end",
+			events: concat(typed("osh"), keys(CtrlA), typed("g"), keys(CtrlE), typed("!")),
+			want:   "gosh!",
+			cursor: 5,
+		},
+		{
+			name:   "delete under the cursor",
+			events: concat(typed("gosh"), keys(Home, Delete, RightArrow, End)),
+			want:   "osh",
+			cursor: 3,
+		},
+		{
+			name:   "ctrl-w deletes the previous word",
+			events: concat(typed("tn  windows  "), keys(CtrlW)),
+			want:   "tn  ",
+			cursor: 4,
+		},
+		{
+			name:   "ctrl-w keeps the text after the cursor",
+			events: concat(typed("foo bar"), keys(LeftArrow, LeftArrow, CtrlW)),
+			want:   "foo ar",
+			cursor: 4,
+		},
+		{
+			name:   "alt-backspace deletes a word",
+			events: concat(typed("foo bar"), []Event{{Key: Backspace, Mod: ModAlt}}),
+			want:   "foo ",
+			cursor: 4,
+		},
+		{
+			name:   "ctrl-u clears the query",
+			events: concat(typed("gosh"), keys(LeftArrow, CtrlU)),
+			want:   "",
+			cursor: 0,
+		},
+		{
+			name:   "cursor stops at the edges",
+			events: concat(typed("ab"), keys(RightArrow, LeftArrow, LeftArrow, LeftArrow, Backspace)),
+			want:   "ab",
+			cursor: 0,
+		},
+		{
+			name:   "alt and ctrl runes are not typed",
+			events: []Event{{Key: Other, Rune: 'x', Mod: ModAlt}, {Key: Other, Rune: ' ', Mod: ModCtrl}},
+			want:   "",
+			cursor: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sel := newTestSelector("a")
+			for _, ev := range tc.events {
+				sel.handleInput(ev, sel.filter())
+			}
+			if got := sel.query.String(); got != tc.want {
+				t.Errorf("Expected query %q, got %q", tc.want, got)
+			}
+			if sel.query.cursor != tc.cursor {
+				t.Errorf("Expected cursor at %d, got %d", tc.cursor, sel.query.cursor)
+			}
+		})
+	}
+}
+
+func TestSelectorNavigationKeys(t *testing.T) {
+	sel := newTestSelector("tn", "fd", "fs")
+
+	testCases := []struct {
+		key  Key
+		want int
+	}{
+		{key: CtrlN, want: 1},
+		{key: CtrlJ, want: 2},
+		{key: CtrlJ, want: 2},
+		{key: CtrlP, want: 1},
+		{key: CtrlK, want: 0},
+		{key: DownArrow, want: 1},
+	}
+
+	for _, tc := range testCases {
+		press(sel, tc.key)
+		if sel.selectionIndex != tc.want {
+			t.Errorf("Expected selection %d after key %d, got %d", tc.want, tc.key, sel.selectionIndex)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package busybox
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares screen with testdata/name.golden
+func checkGolden(t *testing.T, name, screen string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(screen+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected a golden file, run go test -update: %v", err)
+	}
+	if got := screen + "\n"; got != string(want) {
+		t.Errorf("Expected screen %s:\n%s\ngot:\n%s", path, want, got)
+	}
+}
+
+// draw renders sel on a width x height terminal the way the picker loop does
+func draw[T any](sel *selector[T], width, height int) string {
+	vt := NewVirtualTerm(width, height)
+	defer vt.Close()
+
+	sel.resize(width, height)
+	filtered := sel.filter()
+	sel.clampSelection(len(filtered))
+	sel.adjustScroll(filtered)
+	scr := &screen{moveTo: sel.moveTo}
+	fmt.Fprint(vt, scr.draw(sel.render(filtered)))
+	return vt.Screen()
+}
+
+func TestSelectorScreen(t *testing.T) {
+	dirs := []string{
+		"gosh/cmd", "gosh/internal/sessionizer", "gosh/pkg/busybox",
+		"gosh/pkg/github", "dotfiles/nvim", "dotfiles/tmux",
+	}
+	manyDirs := make([]string, 30)
+	for i := range manyDirs {
+		manyDirs[i] = fmt.Sprintf("projects/%02d", i)
+	}
+
+	testCases := []struct {
+		name   string
+		items  []string
+		setup  func(sel *selector[string])
+		keys   []Key
+		height int // 12 by default
+	}{
+		{
+			name:  "filter",
+			items: dirs,
+			setup: func(sel *selector[string]) { sel.query.set("gshbb") },
+		},
+		{
+			name:  "no_results",
+			items: dirs,
+			setup: func(sel *selector[string]) { sel.query.set("node_modules") },
+		},
+		{
+			name:   "scroll",
+			items:  manyDirs,
+			keys:   slices.Repeat([]Key{DownArrow}, 20),
+			height: 20,
+		},
+		{
+			name:  "groups",
+			items: []string{"dev:editor", "dev:shell", "ops:server", "ops:notes"},
+			setup: func(sel *selector[string]) {
+				sel.formatter.GetGroup = func(s string) string { return strings.Split(s, ":")[0] }
+				sel.formatter.Separator = "● {{GROUP}}"
+				sel.reload(sel.items)
+			},
+			keys: []Key{DownArrow, DownArrow},
+		},
+		{
+			name:  "groups_folded",
+			items: []string{"dev:editor", "dev:shell", "ops:server", "ops:notes"},
+			setup: func(sel *selector[string]) {
+				sel.formatter.GetGroup = func(s string) string { return strings.Split(s, ":")[0] }
+				sel.formatter.Separator = "● {{GROUP}}"
+				sel.formatter.GroupItem = func(group string) string { return group }
+				sel.reload(sel.items)
+			},
+			keys: []Key{LeftArrow},
+		},
+		{
+			name:  "multi",
+			items: dirs,
+			setup: func(sel *selector[string]) { sel.multi = true },
+			keys:  []Key{Tab, DownArrow, Tab},
+		},
+		{
+			name:  "preview",
+			items: dirs,
+			setup: func(sel *selector[string]) {
+				sel.preview = "cat.go\nfzf.go\nterm.go"
+				sel.previewPosition = "right"
+			},
+		},
+		{
+			name:  "preview_bottom",
+			items: dirs,
+			setup: func(sel *selector[string]) {
+				sel.preview = "cat.go\nfzf.go\nterm.go"
+				sel.previewPosition = "bottom"
+			},
+		},
+		{
+			name:  "reverse",
+			items: dirs,
+			setup: func(sel *selector[string]) {
+				sel.layout = Layout{Reverse: true, Header: "Sessions\nCtrl-X kill"}
+				sel.header = []string{"Sessions", "Ctrl-X kill"}
+				sel.query.set("gosh")
+			},
+			keys: []Key{DownArrow},
+		},
+		{
+			name:  "border",
+			items: dirs,
+			setup: func(sel *selector[string]) {
+				sel.layout = Layout{Border: true, Prompt: "dir: "}
+				sel.preview = "cat.go\nfzf.go\nterm.go"
+				sel.previewPosition = "bottom"
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sel := newTestSelector(tc.items...)
+			if tc.setup != nil {
+				tc.setup(sel)
+			}
+			height := cmp.Or(tc.height, 12)
+			sel.resize(60, height)
+			for _, key := range tc.keys {
+				press(sel, key)
+			}
+			checkGolden(t, "picker_"+tc.name, draw(sel, 60, height))
+		})
+	}
+}
+
+func TestPickScreen(t *testing.T) {
+	vt := NewVirtualTerm(40, 10)
+	defer vt.Close()
+
+	type result struct {
+		res Result[string]
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		res, err := Pick(slices.Values([]string{"tn", "fd", "fs", "gcat"}), ItemFormatter[string]{
+			ToString: func(s string) string { return s },
+			Term:     vt,
+		}, false)
+		done <- result{res, err}
+	}()
+
+	// Keys are only sent once every item is in, the prompt has no spinner then
+	if !vt.WaitFor(func(screen string) bool { return strings.HasPrefix(screen, ">\n") }, time.Second) {
+		t.Fatalf("Expected the items to be loaded, got:\n%s", vt.Screen())
+	}
+	vt.Type("f")
+	vt.Send(Event{Key: DownArrow}, Event{Key: Enter})
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if !slices.Equal(r.res.Items, []string{"fs"}) {
+			t.Errorf("Expected fs, got %v", r.res.Items)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Enter to close the picker")
+	}
+	checkGolden(t, "pick", vt.Screen())
+
+	vt2 := NewVirtualTerm(40, 10)
+	defer vt2.Close()
+	go vt2.Send(Event{Key: Escape})
+	_, err := Pick(slices.Values([]string{"tn"}), ItemFormatter[string]{
+		ToString: func(s string) string { return s },
+		Term:     vt2,
+	}, false)
+	if !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected ErrCancelled, got %v", err)
+	}
+}
+
+func TestPickInline(t *testing.T) {
+	vt := NewVirtualTerm(40, 10)
+	defer vt.Close()
+	fmt.Fprint(vt, "$ gosh pick\r\n")
+
+	done := make(chan error)
+	go func() {
+		_, err := Pick(slices.Values([]string{"tn", "fd", "fs"}), ItemFormatter[string]{
+			ToString: func(s string) string { return s },
+			Term:     vt,
+			Layout:   &Layout{Height: "6", Header: "Commands"},
+		}, false)
+		done <- err
+	}()
+
+	// Drawn under the command, the rows above are kept
+	if !vt.WaitFor(func(screen string) bool { return strings.HasPrefix(screen, "$ gosh pick\n>\n") }, time.Second) {
+		t.Fatalf("Expected the picker under the command, got:\n%s", vt.Screen())
+	}
+	checkGolden(t, "pick_inline", vt.Screen())
+
+	vt.Send(Event{Key: Escape})
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCancelled) {
+			t.Errorf("Expected ErrCancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Escape to close the picker")
+	}
+
+	// The picker is cleared and the cursor is back where it started
+	if got := vt.Screen(); got != "$ gosh pick" {
+		t.Errorf("Expected only the command left, got %q", got)
+	}
+	if row, col, visible := vt.Cursor(); row != 1 || col != 0 || !visible {
+		t.Errorf("Expected a shown cursor at 1,0, got %d,%d visible=%v", row, col, visible)
+	}
+}
+
+func TestPagerScreen(t *testing.T) {
+	var content strings.Builder
+	for i := range 20 {
+		fmt.Fprintf(&content, "line %d\n", i+1)
+	}
+
+	vt := NewVirtualTerm(50, 8)
+	defer vt.Close()
+	go func() {
+		vt.Type("jjd")
+		vt.Resize(50, 6)
+		vt.Type("q")
+	}()
+
+	pager := NewHighlightedPager("notes.txt", strings.NewReader(content.String())).SetTerm(vt)
+	if err := pager.Run(); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "pager", vt.Screen())
+
+	vt = NewVirtualTerm(50, 8)
+	defer vt.Close()
+	go vt.Send(Event{Key: WheelDown}, Event{Key: WheelDown}, Event{Key: WheelUp}, Event{Key: Escape})
+	pager = NewHighlightedPager("notes.txt", strings.NewReader(content.String())).SetTerm(vt)
+	if err := pager.Run(); err != nil {
+		t.Fatalf("Expected Escape to quit the pager like q, got %v", err)
+	}
+	if got := strings.SplitN(vt.Screen(), "\n", 2)[0]; got != "line 4" {
+		t.Errorf("Expected the wheel to scroll to line 4, got %q", got)
+	}
+
+	vt = NewVirtualTerm(50, 8)
+	defer vt.Close()
+	go vt.Send(Event{Key: CtrlC})
+	pager = NewHighlightedPager("notes.txt", strings.NewReader(content.String())).SetTerm(vt)
+	if err := pager.Run(); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected Ctrl-C to cancel the pager, got %v", err)
+	}
+
+	// Without a terminal the content is printed as it is highlighted
+	var out bytes.Buffer
+	pager = NewHighlightedPager("notes.txt", strings.NewReader("plain text\n")).SetOutput(&out)
+	if err := pager.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := StripANSI(out.String()); got != "plain text\n" {
+		t.Errorf("Expected the content, got %q", got)
+	}
+}
+
+func TestPagerStatusLine(t *testing.T) {
+	for _, width := range []int{0, 2, 3, 12, 80} {
+		vt := NewVirtualTerm(width, 5)
+		pager := NewHighlightedPager("日本語のメモ.txt", strings.NewReader("")).SetTerm(vt)
+
+		got := StripANSI(pager.statusLine(0, 10))
+		if visibleWidth(got) != width || !utf8.ValidString(got) {
+			t.Errorf("Expected a valid status line %d columns wide, got %q", width, got)
+		}
+		if width == 12 && !strings.HasSuffix(got, "...") {
+			t.Errorf("Expected a cut status line to end with an ellipsis, got %q", got)
+		}
+		vt.Close()
+	}
+}
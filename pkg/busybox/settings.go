@@ -0,0 +1,27 @@
+package busybox
+
+// Settings apply to every terminal, picker and pager started after
+// [Configure]. gosh sets them from its configuration.
+type Settings struct {
+	Mouse  bool      // Report clicks and the wheel
+	Layout Layout    // Of pickers that don't choose their own
+	Theme  Theme     // Colors of the picker and style of highlighted code
+	Colors ColorMode // What the terminal shows, usually [DetectColorMode]
+}
+
+var settings = Settings{
+	Mouse:  true,
+	Theme:  Themes["default"],
+	Colors: BasicColors,
+}
+
+// Configure replaces the settings used from now on. The settings are kept
+// as they were when the colors of the theme are invalid.
+func Configure(s Settings) error {
+	p, err := s.Theme.palette(s.Colors)
+	if err != nil {
+		return err
+	}
+	settings, colors = s, p
+	return nil
+}
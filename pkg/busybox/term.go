@@ -1,9 +1,12 @@
 package busybox
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 
 	"golang.org/x/term"
@@ -19,25 +22,92 @@ const (
 	Escape
 	UpArrow
 	DownArrow
+	Tab
+	CtrlA
+	LeftArrow
+	RightArrow
+	Home
+	End
+	PageUp
+	PageDown
+	Insert
+	Delete
+	BackTab // Shift-Tab
+	CtrlB
+	CtrlD
+	CtrlE
+	CtrlF
+	CtrlG
+	CtrlJ
+	CtrlK
+	CtrlL
+	CtrlN
+	CtrlO
+	CtrlP
+	CtrlQ
+	CtrlR
+	CtrlS
+	CtrlT
+	CtrlU
+	CtrlV
+	CtrlW
+	CtrlX
+	CtrlY
+	CtrlZ
+	F1
+	F2
+	F3
+	F4
+	F5
+	F6
+	F7
+	F8
+	F9
+	F10
+	F11
+	F12
+	MouseClick // The left button was pressed at Event.X, Event.Y
+	WheelUp
+	WheelDown
+	Resize // The terminal size changed, GetSize reads the new one
 	Other
 )
 
+// Mod is a set of modifiers held down with a key
+type Mod int
+
+const (
+	ModShift Mod = 1 << iota
+	ModAlt
+	ModCtrl
+)
+
+// Event is a single input event read from the terminal
+type Event struct {
+	Key  Key
+	Rune rune // Set for Other
+	Mod  Mod
+	X, Y int // Cell of mouse events, 1 based
+}
+
 // ANSI escape codes for terminal control
 const (
-	ClearScreen     = "\033[2J\033[H" // Clear screen and move to home
-	ClearToEOL      = "\033[K"        // Clear from cursor to end of line
-	ClearToEOS      = "\033[J"        // Clear from cursor to end of screen
-	MoveCursorHome  = "\033[H"        // Move cursor to home (top-left)
-	ResetCursor     = "\033[0G"       // Move cursor to start of line
-	HideCursor      = "\033[?25l"     // Hide cursor
-	ShowCursor      = "\033[?25h"     // Show cursor
-	InverseVideo    = "\033[7m"       // Inverse/reverse video
-	ResetFormatting = "\033[0m"       // Reset all formatting
-	CRLF            = "\r\n"          // Carriage return + line feed
-	EnterAltScreen  = "\033[?1049h"   // Enter alternate screen
-	ExitAltScreen   = "\033[?1049l"   // Exit alternate screen
-	SaveScreen      = "\033[?47h"     // Save screen
-	RestoreScreen   = "\033[?47l"     // Restore screen
+	ClearScreen     = "\033[2J\033[H"          // Clear screen and move to home
+	ClearToEOL      = "\033[K"                 // Clear from cursor to end of line
+	ClearToEOS      = "\033[J"                 // Clear from cursor to end of screen
+	MoveCursorHome  = "\033[H"                 // Move cursor to home (top-left)
+	ResetCursor     = "\033[0G"                // Move cursor to start of line
+	HideCursor      = "\033[?25l"              // Hide cursor
+	ShowCursor      = "\033[?25h"              // Show cursor
+	InverseVideo    = "\033[7m"                // Inverse/reverse video
+	ResetFormatting = "\033[0m"                // Reset all formatting
+	CRLF            = "\r\n"                   // Carriage return + line feed
+	EnterAltScreen  = "\033[?1049h"            // Enter alternate screen
+	ExitAltScreen   = "\033[?1049l"            // Exit alternate screen
+	SaveScreen      = "\033[?47h"              // Save screen
+	RestoreScreen   = "\033[?47l"              // Restore screen
+	EnableMouse     = "\033[?1000h\033[?1006h" // Report clicks and the wheel as SGR sequences
+	DisableMouse    = "\033[?1006l\033[?1000l" // Stop reporting the mouse
 )
 
 type EscapeCode string
@@ -67,7 +137,15 @@ const (
 	BrightWhite   Color = "\033[97m"
 )
 
+// InColors returns s in the color c. Without colors, see [ColorMode], only
+// attributes like bold are kept.
 func InColors(c Color, s string) string {
+	if settings.Colors == NoColors {
+		c = c.withoutColors()
+	}
+	if c == "" {
+		return s
+	}
 	return fmt.Sprintf("%s%s%s", c, s, Reset)
 }
 
@@ -76,11 +154,17 @@ func MoveCursorTo(row, col int) string {
 }
 
 type Term interface {
-	Start()
-	Close()
+	Start() error
+	Close() error // Restores the terminal, it is safe to call more than once
 	Clear()
 	Read() (Key, rune)
+	Events() <-chan Event // Input as a stream, closed with the terminal
+
+	// Context is cancelled when the terminal is closed or interrupted by a
+	// signal, [context.Cause] is then a [*SignalError]
+	Context() context.Context
 	GetSize() error
+	Write(p []byte) (int, error) // Output goes to the terminal even when stdout is redirected
 
 	// Screen buffer management
 	EnterAltBuffer()
@@ -91,23 +175,97 @@ type Term interface {
 	Height() int
 }
 
+// ErrNoTerminal is returned when there is no terminal to draw on, like
+// under cron, in CI or in an ssh command without a tty
+var ErrNoTerminal = errors.New("not running in a terminal")
+
+// ErrInterrupted matches the [*SignalError] of an interrupted terminal
+var ErrInterrupted = errors.New("interrupted")
+
+// SignalError is the error of a picker, pager or prompt interrupted by a
+// signal while the terminal was open
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return "interrupted by " + e.Signal.String()
+}
+
+func (e *SignalError) Is(target error) bool {
+	return target == ErrInterrupted
+}
+
+// ExitCode is the conventional exit status after the signal, 130 for SIGINT
+func (e *SignalError) ExitCode() int {
+	if sig, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+	return 130
+}
+
 // Terminal implements the [Term] interface
 type Terminal struct {
 	oldState *term.State
-	width    int // Changed to lowercase
-	height   int // Changed to lowercase
+	width    int
+	height   int
 	tty      *os.File
+	mouse    bool // Mouse reporting is on
+	events   chan Event
+	done     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
 }
 
-func NewTerm() Term {
+// NewTerm opens the terminal in raw mode. It fails with [ErrNoTerminal]
+// when the process has no controlling terminal.
+func NewTerm() (Term, error) {
 	t := &Terminal{}
-	t.Start()
-	return t
+	if err := t.Start(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Start puts the terminal in raw mode and reads its input until [Terminal.Close].
+// SIGINT, SIGTERM and SIGHUP cancel the [Terminal.Context] meanwhile instead
+// of ending the process, so the terminal is restored before exiting.
+func (t *Terminal) Start() error {
+	if err := t.startRawMode(); err != nil {
+		return err
+	}
+	t.ctx, t.cancel = context.WithCancelCause(context.Background())
+	t.done = make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go t.signalLoop(sigs, t.done)
+
+	if settings.Mouse {
+		fmt.Fprint(t, EnableMouse)
+		t.mouse = true
+	}
+
+	t.events = make(chan Event)
+	keys := make(chan Event)
+	go t.readLoop(t.tty, keys, t.done)
+	go resizeLoop(keys, t.events, t.done)
+	return nil
 }
 
-func (t *Terminal) Start() {
-	t.startRawMode()
-	t.setupSignalHandler()
+func (t *Terminal) Context() context.Context {
+	return t.ctx
+}
+
+// signalLoop cancels the context with the first of sigs, signals that would
+// otherwise end the process while the terminal is in raw mode
+func (t *Terminal) signalLoop(sigs chan os.Signal, done <-chan struct{}) {
+	defer signal.Stop(sigs)
+
+	select {
+	case sig := <-sigs:
+		t.cancel(&SignalError{Signal: sig})
+	case <-done:
+	}
 }
 
 func (t *Terminal) Width() int {
@@ -119,113 +277,149 @@ func (t *Terminal) Height() int {
 }
 
 func (t *Terminal) EnterAltBuffer() {
-	fmt.Print(EnterAltScreen)
+	fmt.Fprint(t, EnterAltScreen)
 }
 
 func (t *Terminal) ExitAltBuffer() {
-	fmt.Print(ExitAltScreen)
-	fmt.Print(ShowCursor)
+	fmt.Fprint(t, ExitAltScreen)
+	fmt.Fprint(t, ShowCursor)
 }
 
-func (t *Terminal) setupSignalHandler() {
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		<-sigs
-		t.Close()
-		t.Clear()
-		os.Exit(0)
-	}()
+// Write writes p to the tty, or to stderr once the terminal is closed
+func (t *Terminal) Write(p []byte) (int, error) {
+	if t.tty == nil {
+		return os.Stderr.Write(p)
+	}
+	return t.tty.Write(p)
 }
 
 func (t *Terminal) GetSize() error {
-	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	fd := int(os.Stderr.Fd())
+	if t.tty != nil {
+		fd = int(t.tty.Fd())
+	}
+	width, height, err := term.GetSize(fd)
 	if err != nil {
 		return fmt.Errorf("get terminal size: %w", err)
 	}
 
-	t.width = width   // Changed to lowercase
-	t.height = height // Changed to lowercase
+	t.width = width
+	t.height = height
 	return nil
 }
 
-func Quit(t Term) {
-	t.Close()
-	t.Clear()
-	defer os.Exit(0)
-}
-
-func (t *Terminal) startRawMode() {
-	// Open /dev/tty for raw input mode
+func (t *Terminal) startRawMode() error {
+	// Input comes from /dev/tty, stdin may be a pipe
 	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to open /dev/tty: %v", err))
+		return fmt.Errorf("%w: %w", ErrNoTerminal, err)
 	}
-	t.tty = tty
 
 	t.oldState, err = term.MakeRaw(int(tty.Fd()))
 	if err != nil {
-		panic(fmt.Sprintf("Failed to set raw mode: %v", err))
+		tty.Close()
+		return fmt.Errorf("%w: setting raw mode: %w", ErrNoTerminal, err)
 	}
-	fmt.Print(HideCursor)
+	t.tty = tty
+	fmt.Fprint(t, HideCursor)
+	return nil
 }
 
-func (t *Terminal) Close() {
-	fmt.Print(ShowCursor)
-	t.stopRawMode()
+func (t *Terminal) Close() error {
+	if t.tty == nil {
+		return nil
+	}
+	if t.mouse {
+		fmt.Fprint(t, DisableMouse)
+		t.mouse = false
+	}
+	fmt.Fprint(t, ShowCursor)
+	return t.stopRawMode()
 }
 
-func (t *Terminal) stopRawMode() {
+func (t *Terminal) stopRawMode() error {
+	close(t.done)
+	t.cancel(nil)
+
+	var err error
 	if t.oldState != nil {
-		if err := term.Restore(int(t.tty.Fd()), t.oldState); err != nil {
-			panic(fmt.Sprintf("Failed to restore terminal: %v", err))
+		if err = term.Restore(int(t.tty.Fd()), t.oldState); err != nil {
+			err = fmt.Errorf("restoring terminal: %w", err)
 		}
 		t.oldState = nil
 	}
-	if t.tty != nil {
-		t.tty.Close()
-		t.tty = nil
-	}
+	t.tty.Close()
+	t.tty = nil
+	return err
 }
 
 func (t *Terminal) Clear() {
-	fmt.Print(ClearScreen)
+	fmt.Fprint(t, ClearScreen)
 }
 
-// Read reads from the terminal's tty file descriptor
+// Read blocks until the next key is pressed
 func (t *Terminal) Read() (Key, rune) {
-	buf := make([]byte, 3)
-	n, err := t.tty.Read(buf)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to read input: %v", err))
-	}
-	if n == 0 {
+	ev, ok := <-t.events
+	if !ok {
 		return Unknown, 0
 	}
+	return ev.Key, ev.Rune
+}
+
+// Events returns the stream of input events read from the tty
+func (t *Terminal) Events() <-chan Event {
+	return t.events
+}
+
+// readLoop reads the tty until it is closed and decodes what it reads into events
+func (t *Terminal) readLoop(tty *os.File, events chan<- Event, done <-chan struct{}) {
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, 256)
+		for {
+			n, err := tty.Read(buf)
+			if err != nil {
+				return
+			}
+			select {
+			case chunks <- slices.Clone(buf[:n]):
+			case <-done:
+				return
+			}
+		}
+	}()
 
-	return parseKey(buf, n)
-}
-
-func parseKey(buf []byte, n int) (Key, rune) {
-	switch buf[0] {
-	case 3:
-		return CtrlC, 0
-	case 127:
-		return Backspace, 0
-	case 13, 10:
-		return Enter, 0
-	case 27:
-		if n > 1 && buf[1] == '[' {
-			switch buf[2] {
-			case 'A':
-				return UpArrow, 0
-			case 'B':
-				return DownArrow, 0
+	decodeLoop(chunks, events, done, escTimeout)
+}
+
+// resizeLoop forwards keys to events and adds a [Resize] event whenever the
+// process gets SIGWINCH. It closes events once keys is closed or on done.
+func resizeLoop(keys <-chan Event, events chan<- Event, done <-chan struct{}) {
+	defer close(events)
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+
+	for {
+		var ev Event
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return
 			}
+			ev = key
+		case <-winch:
+			ev = Event{Key: Resize}
+		case <-done:
+			return
+		}
+
+		select {
+		case events <- ev:
+		case <-done:
+			return
 		}
-		return Escape, 0
-	default:
-		return Other, rune(buf[0])
 	}
 }
@@ -0,0 +1,129 @@
+package busybox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestResizeLoop(t *testing.T) {
+	keys := make(chan Event)
+	events := make(chan Event)
+	done := make(chan struct{})
+	defer close(done)
+
+	go resizeLoop(keys, events, done)
+
+	next := func() Event {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("Expected an event")
+			return Event{}
+		}
+	}
+
+	keys <- Event{Key: Other, Rune: 'q'}
+	if ev := next(); ev.Key != Other || ev.Rune != 'q' {
+		t.Errorf("Expected the key to be forwarded, got %+v", ev)
+	}
+
+	// The key went through, so the loop is already notified of SIGWINCH
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(); ev.Key != Resize {
+		t.Errorf("Expected a Resize event, got %+v", ev)
+	}
+
+	close(keys)
+	if _, ok := <-events; ok {
+		t.Error("Expected events to be closed after keys")
+	}
+}
+
+func TestSignalLoop(t *testing.T) {
+	term := &Terminal{}
+	term.ctx, term.cancel = context.WithCancelCause(context.Background())
+	done := make(chan struct{})
+	defer close(done)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	go term.signalLoop(sigs, done)
+
+	// Caught, the process keeps running
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-term.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the signal to cancel the context")
+	}
+
+	err := context.Cause(term.Context())
+	var sigErr *SignalError
+	if !errors.As(err, &sigErr) || !errors.Is(err, ErrInterrupted) || sigErr.ExitCode() != 129 {
+		t.Errorf("Expected SIGHUP with exit code 129, got %v", err)
+	}
+}
+
+func TestNewTermWithoutTerminal(t *testing.T) {
+	if f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		f.Close()
+		t.Skip("running in a terminal")
+	}
+
+	if _, err := NewTerm(); !errors.Is(err, ErrNoTerminal) {
+		t.Errorf("Expected ErrNoTerminal, got %v", err)
+	}
+	_, err := Pick(slices.Values([]string{"tn"}), ItemFormatter[string]{
+		ToString: func(s string) string { return s },
+	}, false)
+	if !errors.Is(err, ErrNoTerminal) {
+		t.Errorf("Expected the picker to fail with ErrNoTerminal, got %v", err)
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	vt := NewVirtualTerm(40, 10)
+	defer vt.Close()
+	done := make(chan error)
+	go func() {
+		_, err := Pick(slices.Values([]string{"tn", "fd"}), ItemFormatter[string]{
+			ToString: func(s string) string { return s },
+			Term:     vt,
+		}, false)
+		done <- err
+	}()
+	if !vt.WaitFor(func(screen string) bool { return strings.Contains(screen, "> tn") }, time.Second) {
+		t.Fatalf("Expected the picker, got:\n%s", vt.Screen())
+	}
+	vt.Interrupt(syscall.SIGTERM)
+
+	select {
+	case err := <-done:
+		var sigErr *SignalError
+		if !errors.As(err, &sigErr) || sigErr.ExitCode() != 143 {
+			t.Errorf("Expected SIGTERM with exit code 143, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the signal to close the picker")
+	}
+
+	vt = NewVirtualTerm(40, 10)
+	defer vt.Close()
+	vt.Interrupt(syscall.SIGINT)
+	err := NewHighlightedPager("notes.txt", strings.NewReader("notes\n")).SetTerm(vt).Run()
+	if !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Expected the pager to be interrupted, got %v", err)
+	}
+}
@@ -0,0 +1,375 @@
+package busybox
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ColorMode is how many colors the terminal shows
+type ColorMode int
+
+const (
+	NoColors    ColorMode = iota // Only attributes like bold
+	BasicColors                  // The 16 ANSI colors
+	Colors256                    // The xterm 256 color palette
+	TrueColor                    // Any RGB color
+)
+
+// DetectColorMode tells the colors of the terminal from the environment.
+// NO_COLOR or a dumb TERM turn them off, COLORTERM announces truecolor and
+// a TERM like xterm-256color 256 colors.
+func DetectColorMode() ColorMode {
+	term := os.Getenv("TERM")
+	if os.Getenv("NO_COLOR") != "" || term == "dumb" {
+		return NoColors
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return TrueColor
+	}
+	if strings.Contains(term, "256color") {
+		return Colors256
+	}
+	return BasicColors
+}
+
+// Theme colors the picker and names the chroma style of highlighted code.
+//
+// A color is one of the 16 ANSI names like "cyan" or "bright-black", an
+// index into the 256 color palette like "208" or truecolor like "#88c0d0",
+// optionally after attributes like "bold". Colors the terminal can't show
+// are brought down to the nearest one it can.
+type Theme struct {
+	Prompt  string // The prompt and the counter
+	Pointer string // Marks the current item
+	Marker  string // Marked items and how many there are
+	Match   string // Runes matched by the query
+	Muted   string // Spinner, header, border, separators
+	Error   string // No results
+	Style   string // Chroma style of the pager and previews
+}
+
+// Themes are the themes that can be chosen by name
+var Themes = map[string]Theme{
+	"default": {
+		Prompt:  "cyan",
+		Pointer: "blue",
+		Marker:  "magenta",
+		Match:   "bold green",
+		Muted:   "bright-black",
+		Error:   "red",
+		Style:   "vim",
+	},
+	"nord": {
+		Prompt:  "#88c0d0",
+		Pointer: "#81a1c1",
+		Marker:  "#b48ead",
+		Match:   "bold #a3be8c",
+		Muted:   "#4c566a",
+		Error:   "#bf616a",
+		Style:   "nord",
+	},
+	"dracula": {
+		Prompt:  "#8be9fd",
+		Pointer: "#bd93f9",
+		Marker:  "#ff79c6",
+		Match:   "bold #50fa7b",
+		Muted:   "#6272a4",
+		Error:   "#ff5555",
+		Style:   "dracula",
+	},
+	"monokai": {
+		Prompt:  "#66d9ef",
+		Pointer: "#ae81ff",
+		Marker:  "#f92672",
+		Match:   "bold #a6e22e",
+		Muted:   "#75715e",
+		Error:   "#f92672",
+		Style:   "monokai",
+	},
+	"solarized-dark": {
+		Prompt:  "#2aa198",
+		Pointer: "#268bd2",
+		Marker:  "#d33682",
+		Match:   "bold #859900",
+		Muted:   "#586e75",
+		Error:   "#dc322f",
+		Style:   "solarized-dark",
+	},
+}
+
+// LookupTheme returns the theme called name
+func LookupTheme(name string) (Theme, error) {
+	theme, ok := Themes[name]
+	if !ok {
+		names := slices.Sorted(maps.Keys(Themes))
+		return Theme{}, fmt.Errorf("unknown theme %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return theme, nil
+}
+
+// palette is a [Theme] resolved for a [ColorMode]
+type palette struct {
+	prompt, pointer, marker, match, muted, error Color
+}
+
+// colors is the palette of the configured theme
+var colors = mustPalette(settings.Theme, settings.Colors)
+
+func (t Theme) palette(mode ColorMode) (palette, error) {
+	var p palette
+	for _, c := range []struct {
+		name string
+		spec string
+		to   *Color
+	}{
+		{"prompt", t.Prompt, &p.prompt},
+		{"pointer", t.Pointer, &p.pointer},
+		{"marker", t.Marker, &p.marker},
+		{"match", t.Match, &p.match},
+		{"muted", t.Muted, &p.muted},
+		{"error", t.Error, &p.error},
+	} {
+		color, err := ParseColor(c.spec, mode)
+		if err != nil {
+			return palette{}, fmt.Errorf("%s: %w", c.name, err)
+		}
+		*c.to = color
+	}
+	return p, nil
+}
+
+// Accent colors s like the prompt of the configured theme, for output of
+// commands that goes along with the picker
+func Accent(s string) string {
+	return InColors(colors.prompt, s)
+}
+
+// Emphasize colors s like the runes matched in the picker
+func Emphasize(s string) string {
+	return InColors(colors.match, s)
+}
+
+// Alert colors s like the errors of the picker
+func Alert(s string) string {
+	return InColors(colors.error, s)
+}
+
+func mustPalette(t Theme, mode ColorMode) palette {
+	p, err := t.palette(mode)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+var (
+	colorNames = []string{
+		"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+		"bright-black", "bright-red", "bright-green", "bright-yellow",
+		"bright-blue", "bright-magenta", "bright-cyan", "bright-white",
+	}
+	colorAttributes = map[string]string{
+		"bold":      "1",
+		"dim":       "2",
+		"italic":    "3",
+		"underline": "4",
+		"reverse":   "7",
+	}
+	// Colors of the 16 ANSI colors in xterm
+	basicRGB = [16][3]int{
+		{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+		{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+		{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+		{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+	}
+	cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+)
+
+// ParseColor returns the escape sequence of the color spec as described in
+// [Theme], brought down to mode. An empty spec is no color.
+func ParseColor(spec string, mode ColorMode) (Color, error) {
+	var params []string
+	for _, word := range strings.Fields(strings.ToLower(spec)) {
+		if attr, ok := colorAttributes[word]; ok {
+			params = append(params, attr)
+			continue
+		}
+		param, err := colorParam(word, mode)
+		if err != nil {
+			return "", fmt.Errorf("invalid color %q: %w", spec, err)
+		}
+		if param != "" {
+			params = append(params, param)
+		}
+	}
+	if len(params) == 0 {
+		return "", nil
+	}
+	return Color("\033[" + strings.Join(params, ";") + "m"), nil
+}
+
+// colorParam returns the SGR parameter of the foreground color word in mode
+func colorParam(word string, mode ColorMode) (string, error) {
+	index := slices.Index(colorNames, word)
+	var rgb [3]int
+	switch {
+	case index >= 0:
+	case strings.HasPrefix(word, "#"):
+		n, err := strconv.ParseUint(word[1:], 16, 32)
+		if err != nil || len(word) != 7 {
+			return "", fmt.Errorf("expected #rrggbb")
+		}
+		rgb = [3]int{int(n >> 16), int(n >> 8 & 0xff), int(n & 0xff)}
+	default:
+		n, err := strconv.Atoi(word)
+		if err != nil || n < 0 || n > 255 {
+			return "", fmt.Errorf("expected a color name, 0 to 255 or #rrggbb")
+		}
+		index = n
+	}
+
+	switch {
+	case mode == NoColors:
+		return "", nil
+	case index >= 0 && index < 16:
+		return basicParam(index), nil
+	case index >= 0 && mode >= Colors256:
+		return fmt.Sprintf("38;5;%d", index), nil
+	case index >= 0:
+		rgb = paletteRGB(index)
+	case mode == TrueColor:
+		return fmt.Sprintf("38;2;%d;%d;%d", rgb[0], rgb[1], rgb[2]), nil
+	case mode == Colors256:
+		return fmt.Sprintf("38;5;%d", nearest256(rgb)), nil
+	}
+	return basicParam(nearestBasic(rgb)), nil
+}
+
+// basicParam is the SGR parameter of the i-th of the 16 ANSI colors
+func basicParam(i int) string {
+	if i < 8 {
+		return strconv.Itoa(30 + i)
+	}
+	return strconv.Itoa(90 + i - 8)
+}
+
+// paletteRGB returns the color at index of the 256 color palette
+func paletteRGB(index int) [3]int {
+	switch {
+	case index < 16:
+		return basicRGB[index]
+	case index < 232:
+		i := index - 16
+		return [3]int{cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6]}
+	default:
+		gray := 8 + 10*(index-232)
+		return [3]int{gray, gray, gray}
+	}
+}
+
+// nearest256 returns the index of the color cube or gray ramp entry of the
+// 256 color palette nearest to rgb
+func nearest256(rgb [3]int) int {
+	var cube [3]int
+	for i, v := range rgb {
+		cube[i] = nearestLevel(v)
+	}
+	cubeIndex := 16 + 36*cube[0] + 6*cube[1] + cube[2]
+
+	gray := min(23, max(0, ((rgb[0]+rgb[1]+rgb[2])/3-3)/10))
+	grayIndex := 232 + gray
+	if distance(paletteRGB(grayIndex), rgb) < distance(paletteRGB(cubeIndex), rgb) {
+		return grayIndex
+	}
+	return cubeIndex
+}
+
+func nearestLevel(v int) int {
+	best := 0
+	for i, level := range cubeLevels {
+		if abs(level-v) < abs(cubeLevels[best]-v) {
+			best = i
+		}
+	}
+	return best
+}
+
+// nearestBasic returns the index of the ANSI color with the hue of rgb.
+// The nearest by distance would turn most pastel colors of themes gray.
+func nearestBasic(rgb [3]int) int {
+	high, low := max(rgb[0], rgb[1], rgb[2]), min(rgb[0], rgb[1], rgb[2])
+	if high-low < 40 {
+		// Gray: black, bright black, white or bright white
+		switch light := (rgb[0] + rgb[1] + rgb[2]) / 3; {
+		case light < 64:
+			return 0
+		case light < 160:
+			return 8
+		case light < 224:
+			return 7
+		default:
+			return 15
+		}
+	}
+
+	// The channels above the middle of the range make the color, red is
+	// bit 0, green 1 and blue 2 like the ANSI colors are numbered
+	index := 0
+	for i, v := range rgb {
+		if v > (high+low)/2 {
+			index |= 1 << i
+		}
+	}
+	if high >= 224 {
+		index += 8
+	}
+	return index
+}
+
+func distance(a, b [3]int) int {
+	dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// withoutColors returns c with only its attributes, like bold, for
+// terminals that show no colors
+func (c Color) withoutColors() Color {
+	params, ok := strings.CutPrefix(string(c), "\033[")
+	if params, ok = strings.CutSuffix(params, "m"); !ok {
+		return c
+	}
+
+	var kept []string
+	fields := strings.Split(params, ";")
+	for i := 0; i < len(fields); i++ {
+		n, _ := strconv.Atoi(fields[i])
+		switch {
+		case n == 38 || n == 48:
+			// Extended colors take the mode and an index or r;g;b
+			if i+1 < len(fields) && fields[i+1] == "5" {
+				i += 2
+			} else {
+				i += 4
+			}
+		case n >= 30 && n <= 49, n >= 90 && n <= 107:
+		default:
+			kept = append(kept, fields[i])
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return Color("\033[" + strings.Join(kept, ";") + "m")
+}
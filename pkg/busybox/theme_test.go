@@ -0,0 +1,128 @@
+package busybox
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/alecthomas/chroma/styles"
+)
+
+func TestParseColor(t *testing.T) {
+	testCases := []struct {
+		spec string
+		mode ColorMode
+		want Color
+	}{
+		{spec: "cyan", mode: BasicColors, want: Cyan},
+		{spec: "bright-black", mode: TrueColor, want: BrightBlack},
+		{spec: "bold green", mode: BasicColors, want: matchColor},
+		{spec: "208", mode: Colors256, want: "\033[38;5;208m"},
+		{spec: "208", mode: BasicColors, want: "\033[93m"},
+		{spec: "#88c0d0", mode: TrueColor, want: "\033[38;2;136;192;208m"},
+		{spec: "#88c0d0", mode: Colors256, want: "\033[38;5;110m"},
+		{spec: "#4c566a", mode: Colors256, want: "\033[38;5;240m"},
+		{spec: "#88c0d0", mode: BasicColors, want: "\033[36m"},
+		{spec: "#4c566a", mode: BasicColors, want: BrightBlack},
+		{spec: "Underline #88C0D0", mode: NoColors, want: "\033[4m"},
+		{spec: "cyan", mode: NoColors, want: ""},
+		{spec: "", mode: TrueColor, want: ""},
+	}
+	for _, tc := range testCases {
+		got, err := ParseColor(tc.spec, tc.mode)
+		if err != nil || got != tc.want {
+			t.Errorf("Expected %q for %q in mode %d, got %q (%v)", tc.want, tc.spec, tc.mode, got, err)
+		}
+	}
+
+	for _, spec := range []string{"teal", "#88c0d", "256", "bold -1"} {
+		if _, err := ParseColor(spec, NoColors); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestDetectColorMode(t *testing.T) {
+	testCases := []struct {
+		noColor, colorTerm, term string
+		want                     ColorMode
+	}{
+		{term: "xterm", want: BasicColors},
+		{term: "xterm-256color", want: Colors256},
+		{term: "xterm-256color", colorTerm: "truecolor", want: TrueColor},
+		{term: "tmux-256color", colorTerm: "24bit", want: TrueColor},
+		{term: "xterm-256color", colorTerm: "truecolor", noColor: "1", want: NoColors},
+		{term: "dumb", want: NoColors},
+	}
+	for _, tc := range testCases {
+		t.Setenv("NO_COLOR", tc.noColor)
+		t.Setenv("COLORTERM", tc.colorTerm)
+		t.Setenv("TERM", tc.term)
+		if got := DetectColorMode(); got != tc.want {
+			t.Errorf("Expected mode %d for %+v, got %d", tc.want, tc, got)
+		}
+	}
+}
+
+func TestThemes(t *testing.T) {
+	for name, theme := range Themes {
+		for mode := NoColors; mode <= TrueColor; mode++ {
+			if _, err := theme.palette(mode); err != nil {
+				t.Errorf("Expected theme %s to have valid colors, got %v", name, err)
+			}
+		}
+		if _, ok := styles.Registry[theme.Style]; !ok {
+			t.Errorf("Expected theme %s to use a chroma style, got %q", name, theme.Style)
+		}
+	}
+
+	if _, err := LookupTheme("gruvbox"); err == nil {
+		t.Error("Expected an error for an unknown theme")
+	}
+}
+
+func TestConfigureNoColors(t *testing.T) {
+	old := settings
+	t.Cleanup(func() { Configure(old) })
+
+	if err := Configure(Settings{Theme: Theme{Match: "bold #zz"}}); err == nil {
+		t.Error("Expected an error for an invalid color")
+	}
+	if colors != mustPalette(old.Theme, old.Colors) {
+		t.Error("Expected an invalid theme to keep the colors")
+	}
+
+	if err := Configure(Settings{Theme: Themes["nord"], Colors: NoColors}); err != nil {
+		t.Fatal(err)
+	}
+	if got := InColors(BrightBlue, "gosh/"); got != "gosh/" {
+		t.Errorf("Expected no colors, got %q", got)
+	}
+	if got := InColors(matchColor, "g"); got != "\033[1mg"+string(Reset) {
+		t.Errorf("Expected the match in bold only, got %q", got)
+	}
+	if got := InColors("\033[1;38;5;110;4m", "g"); got != "\033[1;4mg"+string(Reset) {
+		t.Errorf("Expected bold and underline only, got %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := highlightCode(&buf, "go", "package main\n", ""); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "package main\n" {
+		t.Errorf("Expected plain code, got %q", buf.String())
+	}
+}
+
+// Colors the terminal can't show are brought down to the nearest it can
+func ExampleParseColor() {
+	for _, mode := range []ColorMode{TrueColor, Colors256, BasicColors, NoColors} {
+		c, _ := ParseColor("bold #a3be8c", mode)
+		fmt.Printf("%q\n", c)
+	}
+	// Output:
+	// "\x1b[1;38;2;163;190;140m"
+	// "\x1b[1;38;5;144m"
+	// "\x1b[1;32m"
+	// "\x1b[1m"
+}
@@ -0,0 +1,425 @@
+package busybox
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// VirtualTerm is a [Term] without a tty, for testing what the picker and the
+// pager draw. Input is scripted with [VirtualTerm.Send] and output is drawn
+// on a grid of cells the way a terminal would, [VirtualTerm.Screen] returns
+// its text.
+type VirtualTerm struct {
+	mu       sync.Mutex
+	width    int
+	height   int
+	size     [2]int // Size [VirtualTerm.GetSize] changes to
+	cells    [][]Cell
+	row, col int    // Cursor position, 0 based
+	saved    [2]int // Cursor position saved with ESC 7
+	sgr      string // Attributes of the next printed rune
+	pending  []byte // Unfinished sequence or rune of the last Write
+	alt      bool
+	cursor   bool // Cursor shown
+
+	input  chan Event
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+// Cell is a single character on the screen of a [VirtualTerm]
+type Cell struct {
+	Rune rune
+	SGR  string // Parameters of the SGR sequences in effect, like "1;32", "" when reset
+}
+
+// NewVirtualTerm returns a started terminal of width x height
+func NewVirtualTerm(width, height int) *VirtualTerm {
+	t := &VirtualTerm{width: width, height: height, size: [2]int{width, height}, cursor: true}
+	t.cells = t.blank(height)
+	t.Start()
+	return t
+}
+
+func (t *VirtualTerm) Start() error {
+	t.input = make(chan Event)
+	t.events = make(chan Event)
+	t.done = make(chan struct{})
+	t.ctx, t.cancel = context.WithCancelCause(context.Background())
+	go func() {
+		defer close(t.events)
+		for {
+			select {
+			case ev := <-t.input:
+				select {
+				case t.events <- ev:
+				case <-t.done:
+					return
+				}
+			case <-t.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops the input, [VirtualTerm.Send] does nothing afterwards
+func (t *VirtualTerm) Close() error {
+	t.once.Do(func() {
+		close(t.done)
+		t.cancel(nil)
+	})
+	return nil
+}
+
+func (t *VirtualTerm) Context() context.Context {
+	return t.ctx
+}
+
+// Interrupt cancels the context as if the process got sig
+func (t *VirtualTerm) Interrupt(sig os.Signal) {
+	t.cancel(&SignalError{Signal: sig})
+}
+
+func (t *VirtualTerm) Clear() {
+	t.Write([]byte(ClearScreen))
+}
+
+// Read blocks until the next scripted key
+func (t *VirtualTerm) Read() (Key, rune) {
+	ev, ok := <-t.events
+	if !ok {
+		return Unknown, 0
+	}
+	return ev.Key, ev.Rune
+}
+
+func (t *VirtualTerm) Events() <-chan Event {
+	return t.events
+}
+
+// Send scripts events as if they were typed. It returns once the previous
+// event was taken, so everything drawn before that is on the screen.
+func (t *VirtualTerm) Send(events ...Event) {
+	for _, ev := range events {
+		select {
+		case t.input <- ev:
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// Type sends the runes of s as typed keys
+func (t *VirtualTerm) Type(s string) {
+	for _, r := range s {
+		t.Send(Event{Key: Other, Rune: r})
+	}
+}
+
+// Resize sends a [Resize] event, the size changes to width x height once
+// it is read with [VirtualTerm.GetSize]
+func (t *VirtualTerm) Resize(width, height int) {
+	t.mu.Lock()
+	t.size = [2]int{width, height}
+	t.mu.Unlock()
+
+	t.Send(Event{Key: Resize})
+}
+
+func (t *VirtualTerm) GetSize() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	width, height := t.size[0], t.size[1]
+	if width == t.width && height == t.height {
+		return nil
+	}
+	t.width = width
+	cells := t.blank(height)
+	for i := range min(len(t.cells), height) {
+		copy(cells[i], t.cells[i])
+	}
+	t.cells, t.height = cells, height
+	t.row, t.col = min(t.row, height-1), min(t.col, width-1)
+	return nil
+}
+
+func (t *VirtualTerm) Width() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.width
+}
+
+func (t *VirtualTerm) Height() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.height
+}
+
+// EnterAltBuffer clears the screen. Leaving it keeps the last frame on the
+// screen instead of restoring the old one, so tests can look at it.
+func (t *VirtualTerm) EnterAltBuffer() {
+	t.Write([]byte(EnterAltScreen))
+}
+
+func (t *VirtualTerm) ExitAltBuffer() {
+	t.Write([]byte(ExitAltScreen + ShowCursor))
+}
+
+// Screen returns the text on the screen, one line per row without trailing
+// spaces and without the empty rows at the bottom
+func (t *VirtualTerm) Screen() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.screen()
+}
+
+func (t *VirtualTerm) screen() string {
+	lines := make([]string, len(t.cells))
+	for i, cells := range t.cells {
+		var b strings.Builder
+		for _, c := range cells {
+			b.WriteRune(c.Rune)
+		}
+		lines[i] = strings.TrimRight(b.String(), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Cell returns the cell at row and col, 0 based
+func (t *VirtualTerm) Cell(row, col int) Cell {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if row < 0 || row >= len(t.cells) || col < 0 || col >= len(t.cells[row]) {
+		return Cell{}
+	}
+	return t.cells[row][col]
+}
+
+// Cursor returns the cursor position, 0 based, and whether it is shown
+func (t *VirtualTerm) Cursor() (row, col int, visible bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.row, t.col, t.cursor
+}
+
+// WaitFor waits until cond holds for the screen and reports whether it did
+// before timeout. Output that depends on goroutines, like streamed items or
+// previews, is drawn at no particular time.
+func (t *VirtualTerm) WaitFor(cond func(screen string) bool, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		t.mu.Lock()
+		ok := cond(t.screen())
+		t.mu.Unlock()
+		if ok {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Write draws p on the screen. It understands the sequences gosh writes:
+// cursor moves, saving and restoring the cursor, clearing, SGR attributes,
+// the cursor visibility and the alternate screen. Other sequences are ignored.
+func (t *VirtualTerm) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := append(t.pending, p...)
+	t.pending = nil
+	for len(b) > 0 {
+		n := t.draw(b)
+		if n == 0 {
+			t.pending = append([]byte(nil), b...)
+			break
+		}
+		b = b[n:]
+	}
+	return len(p), nil
+}
+
+// draw handles the sequence or rune at the start of b and returns its length,
+// 0 when b ends before it does
+func (t *VirtualTerm) draw(b []byte) int {
+	switch b[0] {
+	case '\x1b':
+		if len(b) < 2 {
+			return 0
+		}
+		switch b[1] {
+		case '7':
+			t.saved = [2]int{t.row, t.col}
+			return 2
+		case '8':
+			t.row, t.col = t.saved[0], t.saved[1]
+			return 2
+		case '[':
+		default:
+			return 2
+		}
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x40 && b[i] <= 0x7e {
+				t.csi(string(b[2:i]), b[i])
+				return i + 1
+			}
+		}
+		return 0
+	case '\r':
+		t.col = 0
+	case '\n':
+		t.lineFeed()
+	case '\b':
+		t.col = max(0, t.col-1)
+	case '\t':
+		t.col = min(t.width-1, (t.col/8+1)*8)
+	default:
+		if !utf8.FullRune(b) {
+			return 0
+		}
+		r, size := utf8.DecodeRune(b)
+		if r >= ' ' {
+			t.print(r)
+		}
+		return size
+	}
+	return 1
+}
+
+// print puts r under the cursor and moves it right, wrapping at the edge
+func (t *VirtualTerm) print(r rune) {
+	if t.col >= t.width {
+		t.col = 0
+		t.lineFeed()
+	}
+	t.cells[t.row][t.col] = Cell{Rune: r, SGR: t.sgr}
+	t.col++
+}
+
+// lineFeed moves the cursor down, scrolling at the bottom
+func (t *VirtualTerm) lineFeed() {
+	if t.row < t.height-1 {
+		t.row++
+		return
+	}
+	t.cells = append(t.cells[1:], t.blank(1)...)
+}
+
+func (t *VirtualTerm) csi(params string, final byte) {
+	if strings.HasPrefix(params, "?") {
+		on := final == 'h'
+		for _, p := range strings.Split(params[1:], ";") {
+			switch p {
+			case "25":
+				t.cursor = on
+			case "47", "1049":
+				if on != t.alt {
+					t.alt = on
+					if on {
+						t.erase(0, 0, t.height-1, t.width)
+					}
+				}
+			}
+		}
+		return
+	}
+
+	args := strings.Split(params, ";")
+	arg := func(i, def int) int {
+		if i >= len(args) {
+			return def
+		}
+		n, err := strconv.Atoi(args[i])
+		if err != nil || n == 0 {
+			return def
+		}
+		return n
+	}
+
+	switch final {
+	case 'H', 'f':
+		t.row = min(t.height, arg(0, 1)) - 1
+		t.col = min(t.width, arg(1, 1)) - 1
+	case 'A':
+		t.row = max(0, t.row-arg(0, 1))
+	case 'B':
+		t.row = min(t.height-1, t.row+arg(0, 1))
+	case 'C':
+		t.col = min(t.width-1, t.col+arg(0, 1))
+	case 'D':
+		t.col = max(0, t.col-arg(0, 1))
+	case 'G':
+		t.col = min(t.width, arg(0, 1)) - 1
+	case 'J':
+		switch arg(0, 0) {
+		case 0:
+			t.erase(t.row, t.col, t.height-1, t.width)
+		case 1:
+			t.erase(0, 0, t.row, t.col+1)
+		case 2, 3:
+			t.erase(0, 0, t.height-1, t.width)
+		}
+	case 'K':
+		switch arg(0, 0) {
+		case 0:
+			t.erase(t.row, t.col, t.row, t.width)
+		case 1:
+			t.erase(t.row, 0, t.row, t.col+1)
+		case 2:
+			t.erase(t.row, 0, t.row, t.width)
+		}
+	case 'm':
+		if params == "" || params == "0" {
+			t.sgr = ""
+		} else if t.sgr == "" {
+			t.sgr = params
+		} else {
+			t.sgr += ";" + params
+		}
+	}
+}
+
+// erase blanks the cells from (row, col) up to (toRow, toCol) exclusive,
+// reading the screen left to right, top to bottom
+func (t *VirtualTerm) erase(row, col, toRow, toCol int) {
+	for r := row; r <= toRow && r < len(t.cells); r++ {
+		from, to := 0, t.width
+		if r == row {
+			from = col
+		}
+		if r == toRow {
+			to = toCol
+		}
+		for c := max(0, from); c < min(to, t.width); c++ {
+			t.cells[r][c] = Cell{Rune: ' '}
+		}
+	}
+}
+
+// blank returns n empty rows
+func (t *VirtualTerm) blank(n int) [][]Cell {
+	rows := make([][]Cell, n)
+	for i := range rows {
+		rows[i] = make([]Cell, t.width)
+		for j := range rows[i] {
+			rows[i][j] = Cell{Rune: ' '}
+		}
+	}
+	return rows
+}
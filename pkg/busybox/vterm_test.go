@@ -0,0 +1,131 @@
+package busybox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtualTermWrite(t *testing.T) {
+	testCases := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{
+			name:   "lines",
+			writes: []string{"gosh" + CRLF + "tn"},
+			want:   "gosh\ntn",
+		},
+		{
+			name:   "cursor moves",
+			writes: []string{MoveCursorTo(2, 3) + "fd", MoveCursorHome + "fs", ResetCursor + "vf"},
+			want:   "vf\n  fd",
+		},
+		{
+			name:   "colors take no cells",
+			writes: []string{InColors(Cyan, "> ") + InColors(matchColor, "g") + "osh"},
+			want:   "> gosh",
+		},
+		{
+			name:   "clear to the end of the line",
+			writes: []string{"notes.txt" + ResetCursor + "gosh" + ClearToEOL},
+			want:   "gosh",
+		},
+		{
+			name:   "clear to the end of the screen",
+			writes: []string{"tn" + CRLF + "fd" + CRLF + "fs" + MoveCursorTo(2, 2) + ClearToEOS},
+			want:   "tn\nf",
+		},
+		{
+			name:   "wraps at the right edge",
+			writes: []string{"0123456789ab"},
+			want:   "0123456789\nab",
+		},
+		{
+			name:   "scrolls at the bottom",
+			writes: []string{"1\r\n2\r\n3\r\n4\r\n5\r\n6"},
+			want:   "2\n3\n4\n5\n6",
+		},
+		{
+			name:   "sequences and runes split across writes",
+			writes: []string{"\x1b", "[3", "1mżó", "\xc5", "\x82w\x1b[0m"},
+			want:   "żółw",
+		},
+		{
+			name:   "saved cursor position",
+			writes: []string{"$ ls" + CRLF + SaveCursorPos + "tn" + RestoreCursorPos + "\033[2B\033[1Cfd"},
+			want:   "$ ls\ntn\n\n fd",
+		},
+		{
+			name:   "alternate screen starts empty",
+			writes: []string{"shell", EnterAltScreen + MoveCursorTo(2, 1) + "picker"},
+			want:   "\npicker",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vt := NewVirtualTerm(10, 5)
+			defer vt.Close()
+			for _, w := range tc.writes {
+				vt.Write([]byte(w))
+			}
+			if got := vt.Screen(); got != tc.want {
+				t.Errorf("Expected screen %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestVirtualTermCells(t *testing.T) {
+	vt := NewVirtualTerm(20, 3)
+	defer vt.Close()
+	vt.Write([]byte(InColors(Blue, ">") + highlightMatches(" gosh", []int{1}, matchColor) + HideCursor))
+
+	if c := vt.Cell(0, 0); c.Rune != '>' || c.SGR != "34" {
+		t.Errorf("Expected a blue >, got %+v", c)
+	}
+	if c := vt.Cell(0, 2); c.Rune != 'g' || c.SGR != "1;32" {
+		t.Errorf("Expected a highlighted g, got %+v", c)
+	}
+	if c := vt.Cell(0, 3); c.Rune != 'o' || c.SGR != "" {
+		t.Errorf("Expected a plain o, got %+v", c)
+	}
+	if row, col, visible := vt.Cursor(); row != 0 || col != 6 || visible {
+		t.Errorf("Expected a hidden cursor at 0,6, got %d,%d visible=%v", row, col, visible)
+	}
+}
+
+func TestVirtualTermSend(t *testing.T) {
+	vt := NewVirtualTerm(20, 3)
+
+	go func() {
+		vt.Type("q")
+		vt.Resize(30, 4)
+	}()
+
+	if key, r := vt.Read(); key != Other || r != 'q' {
+		t.Errorf("Expected q, got %d %q", key, r)
+	}
+	if key, _ := vt.Read(); key != Resize {
+		t.Errorf("Expected a Resize event, got %d", key)
+	}
+	if vt.Width() != 20 || vt.Height() != 3 {
+		t.Errorf("Expected the size to change with GetSize, got %dx%d", vt.Width(), vt.Height())
+	}
+	vt.GetSize()
+	if vt.Width() != 30 || vt.Height() != 4 {
+		t.Errorf("Expected 30x4, got %dx%d", vt.Width(), vt.Height())
+	}
+
+	vt.Close()
+	select {
+	case _, ok := <-vt.Events():
+		if ok {
+			t.Error("Expected no events after Close")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the events to be closed with the terminal")
+	}
+	vt.Send(Event{Key: Enter}) // Does not block once closed
+}
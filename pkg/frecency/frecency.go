@@ -0,0 +1,214 @@
+// Package frecency ranks things by how often and how recently they were
+// chosen, like zoxide does for directories.
+package frecency
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	maxVisits  = 10   // Visits kept per entry to weigh its recency
+	maxEntries = 1000 // Entries kept per store, the lowest ranked are dropped
+)
+
+// Store remembers when each key was chosen. Keys are usually paths or
+// session names. The zero Store is empty and ready to use, as part of
+// something saved on its own.
+type Store struct {
+	path    string
+	now     func() time.Time
+	Entries map[string]*Entry `json:"entries"`
+}
+
+// Entry is what a store knows about a key
+type Entry struct {
+	Count  int         `json:"count"`  // How many times the key was chosen
+	Visits []time.Time `json:"visits"` // When it was chosen last, oldest first
+}
+
+// Load reads the store kept at path. A store that doesn't exist yet is
+// empty. On error the store is empty too and can still be used and saved.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, Entries: make(map[string]*Entry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("reading frecency store: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		s.Entries = make(map[string]*Entry)
+		return s, fmt.Errorf("parsing frecency store %s: %w", path, err)
+	}
+	if s.Entries == nil {
+		s.Entries = make(map[string]*Entry)
+	}
+	return s, nil
+}
+
+// Add records that key was chosen now
+func (s *Store) Add(key string) {
+	if s.Entries == nil {
+		s.Entries = make(map[string]*Entry)
+	}
+	e, ok := s.Entries[key]
+	if !ok {
+		e = &Entry{}
+		s.Entries[key] = e
+	}
+	e.Count++
+	e.Visits = append(e.Visits, s.time())
+	if len(e.Visits) > maxVisits {
+		e.Visits = slices.Clone(e.Visits[len(e.Visits)-maxVisits:])
+	}
+
+	if len(s.Entries) > maxEntries {
+		for _, key := range s.Keys()[maxEntries:] {
+			delete(s.Entries, key)
+		}
+	}
+}
+
+// Rename moves what the store knows about key to newKey, on top of what it
+// already knew about newKey
+func (s *Store) Rename(key, newKey string) {
+	e, ok := s.Entries[key]
+	if !ok || key == newKey {
+		return
+	}
+	delete(s.Entries, key)
+	if old, ok := s.Entries[newKey]; ok {
+		e.Count += old.Count
+		e.Visits = append(e.Visits, old.Visits...)
+		slices.SortFunc(e.Visits, time.Time.Compare)
+		if len(e.Visits) > maxVisits {
+			e.Visits = slices.Clone(e.Visits[len(e.Visits)-maxVisits:])
+		}
+	}
+	s.Entries[newKey] = e
+}
+
+// Score is how often key was chosen weighed by how recently, 0 for a key
+// never chosen
+func (s *Store) Score(key string) float64 {
+	e, ok := s.Entries[key]
+	if !ok || len(e.Visits) == 0 {
+		return 0
+	}
+	now := s.time()
+	var weights float64
+	for _, v := range e.Visits {
+		weights += weight(now.Sub(v))
+	}
+	// The kept visits stand for all of them
+	return float64(e.Count) * weights / float64(len(e.Visits))
+}
+
+// time returns the time it is for the store
+func (s *Store) time() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// weight is how much a visit counts after age
+func weight(age time.Duration) float64 {
+	switch {
+	case age < 4*time.Hour:
+		return 100
+	case age < 24*time.Hour:
+		return 80
+	case age < 7*24*time.Hour:
+		return 60
+	case age < 30*24*time.Hour:
+		return 40
+	case age < 90*24*time.Hour:
+		return 20
+	}
+	return 10
+}
+
+// Keys returns the keys of the store, highest score first
+func (s *Store) Keys() []string {
+	keys := slices.Sorted(maps.Keys(s.Entries))
+	s.Sort(keys)
+	return keys
+}
+
+// Sort orders keys by score, highest first. Keys with the same score, like
+// the ones never chosen, keep their order.
+func (s *Store) Sort(keys []string) {
+	SortFunc(s, keys, func(key string) string { return key })
+}
+
+// SortFunc orders items by the score of their key, highest first, see
+// [Store.Sort]
+func SortFunc[T any](s *Store, items []T, key func(T) string) {
+	scores := make(map[string]float64, len(items))
+	for _, item := range items {
+		k := key(item)
+		scores[k] = s.Score(k)
+	}
+	slices.SortStableFunc(items, func(a, b T) int {
+		return cmp.Compare(scores[key(b)], scores[key(a)])
+	})
+}
+
+// Prune drops the keys for which keep returns false and returns them
+func (s *Store) Prune(keep func(key string) bool) []string {
+	var pruned []string
+	for _, key := range slices.Sorted(maps.Keys(s.Entries)) {
+		if !keep(key) {
+			delete(s.Entries, key)
+			pruned = append(pruned, key)
+		}
+	}
+	return pruned
+}
+
+// Save writes the store back to where it was loaded from, see
+// [WriteJSON] for two commands saving at once
+func (s *Store) Save() error {
+	if err := WriteJSON(s.path, s); err != nil {
+		return fmt.Errorf("saving frecency store: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON writes v as JSON to path, creating its directory. The file is
+// replaced with a rename, so readers never see it half written. Writes
+// aren't merged, when two processes save at once the last one wins.
+func WriteJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("saving %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,173 @@
+package frecency
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// at returns a store whose clock reads *now
+func at(now *time.Time) *Store {
+	return &Store{now: func() time.Time { return *now }, Entries: make(map[string]*Entry)}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frecency", "dirs.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Entries) != 0 {
+		t.Fatalf("Expected an empty store, got %+v", s.Entries)
+	}
+
+	s.Add("/home/gosh")
+	s.Add("/home/notes")
+	s.Add("/home/gosh")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/home/gosh", "/home/notes"}; !slices.Equal(s.Keys(), want) {
+		t.Errorf("Expected %q, got %q", want, s.Keys())
+	}
+	if e := s.Entries["/home/gosh"]; e.Count != 2 || len(e.Visits) != 2 {
+		t.Errorf("Expected two visits of /home/gosh, got %+v", e)
+	}
+
+	// A broken file is reported, the store still works
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Load(path)
+	if err == nil {
+		t.Error("Expected an error for a broken store")
+	}
+	s.Add("/home/gosh")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScore(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := at(&now)
+
+	// Chosen often a while ago against once just now
+	for range 5 {
+		s.Add("old")
+	}
+	now = now.Add(60 * 24 * time.Hour)
+	s.Add("new")
+
+	testCases := []struct {
+		key  string
+		want float64
+	}{
+		{key: "old", want: 5 * 20},
+		{key: "new", want: 100},
+		{key: "never", want: 0},
+	}
+	for _, tc := range testCases {
+		if got := s.Score(tc.key); got != tc.want {
+			t.Errorf("Expected %s to score %v, got %v", tc.key, tc.want, got)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	s.Add("old")
+	if got := s.Entries["old"]; got.Count != 6 || len(got.Visits) != 6 {
+		t.Errorf("Expected a sixth visit, got %+v", got)
+	}
+	// Five visits at 20 and one at 100, six times
+	if got := s.Score("old"); got != 6*(5*20+100)/6 {
+		t.Errorf("Expected the recent visit to count, got %v", got)
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := at(&now)
+
+	for range maxVisits + 5 {
+		s.Add("gosh")
+	}
+	if e := s.Entries["gosh"]; e.Count != maxVisits+5 || len(e.Visits) != maxVisits {
+		t.Errorf("Expected %d visits kept of %d, got %+v", maxVisits, maxVisits+5, e)
+	}
+
+	for i := range maxEntries {
+		s.Add(fmt.Sprint(i))
+	}
+	if len(s.Entries) != maxEntries || s.Entries["gosh"] == nil {
+		t.Errorf("Expected %d entries with the most chosen kept, got %d", maxEntries, len(s.Entries))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	s := at(&now)
+	for _, key := range []string{"gosh", "dead", "notes", "gone"} {
+		s.Add(key)
+	}
+
+	pruned := s.Prune(func(key string) bool { return key != "dead" && key != "gone" })
+	if want := []string{"dead", "gone"}; !slices.Equal(pruned, want) {
+		t.Errorf("Expected %q to be pruned, got %q", want, pruned)
+	}
+	if want := []string{"gosh", "notes"}; !slices.Equal(s.Keys(), want) {
+		t.Errorf("Expected %q to be left, got %q", want, s.Keys())
+	}
+}
+
+func TestRename(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := at(&now)
+	s.Add("dev")
+	s.Add("dev")
+	s.Add("notes")
+	now = now.Add(time.Hour)
+	s.Add("ops")
+
+	s.Rename("dev", "work")
+	if _, ok := s.Entries["dev"]; ok {
+		t.Error("Expected dev to be gone after the rename")
+	}
+	if e := s.Entries["work"]; e == nil || e.Count != 2 {
+		t.Errorf("Expected work to take over the two visits of dev, got %+v", e)
+	}
+
+	// Renamed onto a known key the visits add up, oldest first
+	s.Rename("ops", "notes")
+	e := s.Entries["notes"]
+	if e.Count != 2 || len(e.Visits) != 2 || !e.Visits[0].Before(e.Visits[1]) {
+		t.Errorf("Expected the visits of ops and notes together, got %+v", e)
+	}
+
+	s.Rename("never", "chosen")
+	if _, ok := s.Entries["chosen"]; ok {
+		t.Error("Expected renaming an unknown key to do nothing")
+	}
+}
+
+// Items that were never chosen keep their order after the chosen ones
+func ExampleSortFunc() {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := at(&now)
+	s.Add("notes")
+	s.Add("gosh")
+	s.Add("gosh")
+
+	sessions := []string{"dotfiles", "gosh", "work", "notes"}
+	SortFunc(s, sessions, func(name string) string { return name })
+	fmt.Println(sessions)
+	// Output:
+	// [gosh notes dotfiles work]
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -18,9 +19,14 @@ import (
 )
 
 const (
-	USER_REPOS = "https://api.github.com/users/DnFreddie/repos"
+	USER_REPOS = "https://api.github.com/users/%s/repos?per_page=100"
 )
 
+// UserRepos returns the GitHub API URL listing the repositories of user
+func UserRepos(user string) string {
+	return fmt.Sprintf(USER_REPOS, user)
+}
+
 type Repo struct {
 	Owner    string `json:"login"`
 	URL      string `json:"clone_url"`
@@ -28,6 +34,10 @@ type Repo struct {
 	FullName string `json:"full_name"`
 	Version  string `json:"version"`
 	Path     string
+
+	Description string `json:"description"`
+	Language    string `json:"language"`
+	Stars       int    `json:"stargazers_count"`
 }
 
 func NewRepo(repoPathOrURL string) (*Repo, error) {
@@ -74,34 +84,75 @@ type RepoManager struct {
 }
 
 func NewRepoManager(url string, client *http.Client) (*RepoManager, error) {
-	return fetchRepos(url, client)
+	var repos []Repo
+	for repo, err := range FetchRepos(url, client) {
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return &RepoManager{Repos: repos}, nil
 }
 
-func fetchRepos(url string, client *http.Client) (*RepoManager, error) {
+// FetchRepos yields the repositories listed at url as the pages come in,
+// following the Link header GitHub paginates with. It stops after yielding
+// the first error.
+func FetchRepos(url string, client *http.Client) iter.Seq2[Repo, error] {
+	return func(yield func(Repo, error) bool) {
+		for url != "" {
+			repos, next, err := fetchRepos(url, client)
+			if err != nil {
+				yield(Repo{}, err)
+				return
+			}
+			for _, repo := range repos {
+				if !yield(repo, nil) {
+					return
+				}
+			}
+			url = next
+		}
+	}
+}
+
+// fetchRepos fetches one page of repositories and the URL of the next one
+func fetchRepos(url string, client *http.Client) ([]Repo, string, error) {
 	var repos []Repo
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching %v: %w", url, err)
+		return nil, "", fmt.Errorf("error fetching %v: %w", url, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error: received non-200 response code %d for %s", resp.StatusCode, url)
+		return nil, "", fmt.Errorf("error: received non-200 response code %d for %s", resp.StatusCode, url)
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-		return nil, fmt.Errorf("error parsing JSON: %w", err)
+		return nil, "", fmt.Errorf("error parsing JSON: %w", err)
 	}
 
-	return &RepoManager{Repos: repos}, nil
+	return repos, nextLink(resp.Header.Get("Link")), nil
+}
+
+// nextLink returns the rel="next" URL of a Link header, or "" on the last page
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(target), "<>")
+	}
+	return ""
 }
 
 type RepoExistErr struct {
@@ -153,7 +204,7 @@ func (r *Repo) Clone(dir string) error {
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to clone repository: %v", err)
 	}
-	fmt.Printf("Successfully cloned %s", r.FullName)
+	slog.Info("Successfully cloned", "name", r.FullName)
 	return nil
 }
 
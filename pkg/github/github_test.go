@@ -3,8 +3,10 @@ package github
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -18,7 +20,7 @@ func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 
 // Test [NewRepo] that  creates new repos with two formats
 // normal url and  Owner/Name scenarion
-func Example_NewRepo() {
+func ExampleNewRepo() {
 	// Example with a URL
 	repo1, err := NewRepo("https://github.com/DnFreddie/gosh.git")
 	if err != nil {
@@ -110,3 +112,40 @@ func ExampleRepoManager_FilterByOwner() {
 	// repo1
 	// repo3
 }
+
+func TestFetchRepos(t *testing.T) {
+	pages := map[string]string{
+		"1": `[{"name": "gosh"}, {"name": "goseq"}]`,
+		"2": `[{"name": "dotfiles"}]`,
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos?page=2>; rel="next", <%s/repos?page=2>; rel="last"`, srv.URL, srv.URL))
+		}
+		body, ok := pages[page]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	var names []string
+	for repo, err := range FetchRepos(srv.URL+"/repos?page=1", srv.Client()) {
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		names = append(names, repo.Name)
+	}
+	if want := []string{"gosh", "goseq", "dotfiles"}; !slices.Equal(names, want) {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+
+	_, err := NewRepoManager(srv.URL+"/repos?page=3", srv.Client())
+	if err == nil {
+		t.Error("Expected an error for a missing page, got none")
+	}
+}
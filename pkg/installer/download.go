@@ -3,6 +3,7 @@ package installer
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
@@ -13,36 +14,38 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"golang.org/x/term"
 )
 
-func (i *Installer) installRepo(repo *Repo, tempDir string) error {
+func (i *Installer) installRepo(repo *Repo, tempDir string) ([]string, error) {
 	var versionRegex = regexp.MustCompile(`_(v?\d+\.\d+\.\d+)`)
 	archivePath, err := i.Download(repo.Links.ArchiveUrl, tempDir)
 	if err != nil {
 		sanitizedURL := versionRegex.ReplaceAllString(repo.Links.ArchiveUrl, "")
 		archivePath, err = i.Download(sanitizedURL, tempDir)
 		if err != nil {
-			return fmt.Errorf("download failed with sanitized URL: %w", err)
+			return nil, fmt.Errorf("download failed with sanitized URL: %w", err)
 		}
 	}
 
 	file, err := os.Open(archivePath)
 	if err != nil {
-		return fmt.Errorf("failed to open archive: %w", err)
+		return nil, fmt.Errorf("failed to open archive: %w", err)
 	}
 	defer file.Close()
 
 	installedFiles, err := i.ExtractTarGz(file, tempDir)
 	if err != nil {
-		return fmt.Errorf("extraction failed: %w", err)
+		return nil, fmt.Errorf("extraction failed: %w", err)
 	}
 
-	fmt.Printf("Successfully installed %s/%s. Files: %v\n", repo.Owner, repo.Name, installedFiles)
-	return nil
+	slog.Info("Successfully installed", "repo", repo.Owner+"/"+repo.Name, "version", repo.Version, "files", installedFiles)
+	return installedFiles, nil
 }
 
 func (i *Installer) Download(downloadURL, destDir string) (string, error) {
-	fmt.Println("Starting download", "Download URL:", downloadURL)
+	slog.Debug("Starting download", "url", downloadURL)
 	parsedURL, err := url.ParseRequestURI(downloadURL)
 	if err != nil {
 		return "", fmt.Errorf("invalid URL: %w", err)
@@ -53,7 +56,7 @@ func (i *Installer) Download(downloadURL, destDir string) (string, error) {
 		return "", fmt.Errorf("could not determine filename from URL")
 	}
 
-	fmt.Printf("Downloading %s...\n", fileName)
+	slog.Info("Downloading", "file", fileName)
 
 	filePath := filepath.Join(destDir, fileName)
 	file, err := os.Create(filePath)
@@ -85,20 +88,26 @@ func (i *Installer) Download(downloadURL, destDir string) (string, error) {
 
 	done <- true
 
-	fmt.Println("\nDownload complete.")
+	slog.Debug("Download complete", "file", filePath)
 	return filePath, nil
 }
 
+// startSpinner draws on stderr, and only when it is a terminal someone watches
 func startSpinner(done chan bool) {
+	if !term.IsTerminal(int(os.Stderr.Fd())) || !slog.Default().Enabled(context.Background(), slog.LevelInfo) {
+		<-done
+		return
+	}
+
 	spinner := []string{"|", "/", "-", "\\"}
 	i := 0
 	for {
 		select {
 		case <-done:
-			fmt.Print("\r")
+			fmt.Fprint(os.Stderr, "\r\033[K")
 			return
 		default:
-			fmt.Printf("\rDownloading... %s", spinner[i%len(spinner)])
+			fmt.Fprintf(os.Stderr, "\rDownloading... %s", spinner[i%len(spinner)])
 			time.Sleep(100 * time.Millisecond)
 			i++
 		}
@@ -12,12 +12,6 @@ import (
 	"strings"
 )
 
-var TOOLBOX = []string{
-	"cli/cli",
-	"mikefarah/yq",
-	"junegunn/fzf",
-}
-
 // Config holds configuration for the installer
 type Config struct {
 	TargetDir string // Directory where executables will be installed (default: ~/.local/bin)
@@ -38,6 +32,13 @@ type Repo struct {
 	Links   DownloadLinks
 }
 
+// Result describes what [Installer.Install] put in place for one repository
+type Result struct {
+	Repo    string   `json:"repo"`
+	Version string   `json:"version"`
+	Files   []string `json:"files"`
+}
+
 // DownloadLinks holds URLs for downloading assets and checksums
 type DownloadLinks struct {
 	ArchiveUrl string
@@ -114,7 +115,7 @@ func (i *Installer) fetchReleases() error {
 
 func (r *Repo) fetchRelease(client *http.Client) error {
 	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", r.Owner, r.Name)
-	slog.Info("Trying to fetch", "url", apiURL)
+	slog.Debug("Trying to fetch", "url", apiURL)
 	resp, err := client.Get(apiURL)
 	if err != nil {
 		return fmt.Errorf("error fetching release: %w", err)
@@ -154,21 +155,30 @@ func validateRepoUrl(repoUrl string) ([]string, error) {
 	return parts, nil
 }
 
-func (i *Installer) Install() error {
+// Install downloads the latest release of every repository and returns
+// the executables it installed
+func (i *Installer) Install() ([]Result, error) {
 	if err := i.fetchReleases(); err != nil {
-		return err
+		return nil, err
 	}
 
 	tempDir, err := i.createTempDir()
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 	defer os.RemoveAll(tempDir)
 
+	var results []Result
 	for _, repo := range i.repos {
-		if err := i.installRepo(repo, tempDir); err != nil {
-			return fmt.Errorf("failed to install %s/%s: %w", repo.Owner, repo.Name, err)
+		files, err := i.installRepo(repo, tempDir)
+		if err != nil {
+			return results, fmt.Errorf("failed to install %s/%s: %w", repo.Owner, repo.Name, err)
 		}
+		results = append(results, Result{
+			Repo:    repo.Owner + "/" + repo.Name,
+			Version: repo.Version,
+			Files:   files,
+		})
 	}
-	return nil
+	return results, nil
 }
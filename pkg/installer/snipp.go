@@ -3,12 +3,14 @@ package installer
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/DnFreddie/goseq/pkg/terminal"
+	"github.com/DnFreddie/gosh/pkg/busybox"
 )
 
 type Snippet struct {
@@ -17,17 +19,13 @@ type Snippet struct {
 	Content bytes.Buffer
 }
 
-func (s *Snippet) PrintSnippet() {
-
-	green := "\033[32m"
-	blue := "\033[34m"
-	reset := "\033[0m"
-
-	fmt.Println(strings.Repeat("-", 30))
-	fmt.Println("Chosen Snippet:")
-	fmt.Println(strings.Repeat("-", 30))
-	fmt.Printf("Language: %s%s%s\n", green, s.Lang, reset)
-	fmt.Printf("Name: %s%s%s\n", blue, s.Name, reset)
+// PrintSnippet writes a short summary of the snippet to w
+func (s *Snippet) PrintSnippet(w io.Writer) {
+	fmt.Fprintln(w, strings.Repeat("-", 30))
+	fmt.Fprintln(w, "Chosen Snippet:")
+	fmt.Fprintln(w, strings.Repeat("-", 30))
+	fmt.Fprintf(w, "Language: %s\n", busybox.Emphasize(s.Lang))
+	fmt.Fprintf(w, "Name: %s\n", busybox.Accent(s.Name))
 }
 
 type SnipScanner struct {
@@ -138,28 +136,38 @@ func GetSnippet(r io.Reader) (*Snippet, error) {
 
 }
 
-func ChoseSnippet() (string, error) {
-	snippetsDir := "/home/aura/.dotfiles/snippets/"
+// ChoseSnippet lets the user pick one of the *.md files in snippetsDir.
+// The preview shows the snippet highlighted with the chroma style.
+func ChoseSnippet(snippetsDir string, style string) (string, error) {
 	files, err := filepath.Glob(filepath.Join(snippetsDir, "*.md"))
 	if err != nil {
 		return "", err
 	}
 
-	fileNames := make([]map[string]string, len(files))
-	for i, file := range files {
-		fileNames[i] = map[string]string{
-			filepath.Base(file): file,
-		}
-	}
-	choice, err := terminal.RunTerm(fileNames)
+	return busybox.RunTermGrouped(files, busybox.ItemFormatter[string]{
+		ToString: filepath.Base,
+		Preview: func(_ context.Context, file string) string {
+			return previewSnippet(file, style)
+		},
+		History: "snip",
+	})
+}
 
+func previewSnippet(file string, style string) string {
+	f, err := os.Open(file)
 	if err != nil {
-		return "", err
+		return err.Error()
 	}
-	for _, v := range choice {
+	defer f.Close()
 
-		return v, nil
+	snippet, err := GetSnippet(f)
+	if err != nil {
+		return err.Error()
 	}
-	return "", fmt.Errorf("This shouldHave happend")
 
+	body, err := busybox.Highlight(snippet.Lang, snippet.Content.String(), style)
+	if err != nil {
+		body = snippet.Content.String()
+	}
+	return fmt.Sprintf("%s (%s)\n\n%s", snippet.Name, snippet.Lang, body)
 }
@@ -1,8 +1,12 @@
 package installer
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+	"testing"
+
+	"github.com/DnFreddie/gosh/pkg/busybox"
 )
 
 // Test the [getName] function, which returns the name of the snippet
@@ -24,7 +28,7 @@ func Example_getCodeFormat() {
 }
 
 // Tests the [GetSnippet] which returns a [Snippet]
-func Example_GetSnippet() {
+func ExampleGetSnippet() {
 
 	test := " ```bash\n##NAME:TEST.t##\nthis\nis\ntest\n```\n"
 
@@ -46,3 +50,26 @@ func Example_GetSnippet() {
 	// is
 	// test
 }
+
+func TestPrintSnippetColors(t *testing.T) {
+	theme := busybox.Themes["default"]
+	t.Cleanup(func() { busybox.Configure(busybox.Settings{Theme: theme, Colors: busybox.BasicColors}) })
+
+	s := &Snippet{Lang: "go", Name: "server"}
+	for _, tc := range []struct {
+		mode busybox.ColorMode
+		want string
+	}{
+		{mode: busybox.BasicColors, want: "Language: \033[1;32mgo\033[0m\nName: \033[36mserver\033[0m\n"},
+		{mode: busybox.NoColors, want: "Language: \033[1mgo\033[0m\nName: server\n"},
+	} {
+		if err := busybox.Configure(busybox.Settings{Theme: theme, Colors: tc.mode}); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		s.PrintSnippet(&buf)
+		if got := buf.String(); !strings.HasSuffix(got, tc.want) {
+			t.Errorf("Expected the theme colors for mode %d, got %q", tc.mode, got)
+		}
+	}
+}